@@ -0,0 +1,65 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"minimal_sns_app/repository"
+	"os"
+)
+
+// checkFriendshipsCommand is the subcommand running the friendship invariant checker instead of the server:
+//
+//	app check-friendships [-limit 100] [-repair] [-batch-size 500]
+//
+// The report is written to stdout as JSON, the logs go to stderr.
+const checkFriendshipsCommand = "check-friendships"
+
+// runCheckFriendships reports the inconsistencies between friend links, friend requests and blocks,
+// repairing them first when -repair is given.
+func runCheckFriendships(db *sql.DB, args []string) error {
+	flags := flag.NewFlagSet(checkFriendshipsCommand, flag.ContinueOnError)
+	limit := flags.Int("limit", 100, "number of inconsistencies of every kind to list")
+	repair := flags.Bool("repair", false, "repair the inconsistencies before reporting")
+	batchSize := flags.Int("batch-size", 500, "number of rows repaired per transaction")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *limit < 0 {
+		return usageError(flags, "-limit must not be negative")
+	}
+	if *batchSize <= 0 {
+		return usageError(flags, "-batch-size must be positive")
+	}
+
+	log.SetOutput(os.Stderr)
+	invariantRepo := repository.NewInvariantRepository(db)
+
+	var repaired map[string]int
+	if *repair {
+		var err error
+		if repaired, err = invariantRepo.RepairFriendships(*batchSize); err != nil {
+			return err
+		}
+	}
+
+	report, err := invariantRepo.CheckFriendships(*limit)
+	if err != nil {
+		return err
+	}
+	report.Repaired = repaired
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// usageError prints the usage of the subcommand and returns the error explaining what was wrong with the flags.
+func usageError(flags *flag.FlagSet, message string) error {
+	fmt.Fprintf(flags.Output(), "%s: %s\n", checkFriendshipsCommand, message)
+	flags.Usage()
+	return errors.New(message)
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/kelseyhightower/envconfig"
 	"log"
 	"sync"
+	"time"
 )
 
 var (
@@ -12,8 +13,10 @@ var (
 )
 
 type Config struct {
-	Server ServerConfig
-	DB     DBConfig
+	Server        ServerConfig
+	DB            DBConfig
+	FriendRequest FriendRequestConfig
+	User          UserConfig
 }
 
 type ServerConfig struct {
@@ -25,6 +28,24 @@ type DBConfig struct {
 	DataSource string `default:"root:@(db:3306)/app"`
 }
 
+// FriendRequestConfig controls how long pending friend requests live.
+// A pending request older than TTL is expired by a sweeper running every SweepInterval,
+// which updates at most SweepBatchSize requests per transaction. The sweeper is disabled unless all three are positive.
+type FriendRequestConfig struct {
+	TTL            time.Duration `default:"720h"`
+	SweepInterval  time.Duration `default:"1h" split_words:"true"`
+	SweepBatchSize int           `default:"500" split_words:"true"`
+}
+
+// UserConfig controls how long a deactivated user can be restored.
+// A user deactivated more than DeactivationGracePeriod ago is deleted for good by a purger running every PurgeInterval,
+// which deletes at most PurgeBatchSize users per transaction. The purger is disabled unless all three are positive.
+type UserConfig struct {
+	DeactivationGracePeriod time.Duration `default:"720h" split_words:"true"`
+	PurgeInterval           time.Duration `default:"1h" split_words:"true"`
+	PurgeBatchSize          int           `default:"100" split_words:"true"`
+}
+
 const apiVersion = "v1"
 const ApiPrefix = "/minimal_sns_api/" + apiVersion
 
@@ -36,6 +57,12 @@ func Get() Config {
 		if err := envconfig.Process("db", &conf.DB); err != nil {
 			log.Fatal(err.Error())
 		}
+		if err := envconfig.Process("friend_request", &conf.FriendRequest); err != nil {
+			log.Fatal(err.Error())
+		}
+		if err := envconfig.Process("user", &conf.User); err != nil {
+			log.Fatal(err.Error())
+		}
 	})
 	return conf
 }
@@ -0,0 +1,7 @@
+package models
+
+// FollowCounts represents how many users follow a user and how many users they follow.
+type FollowCounts struct {
+	Followers int `json:"followers" db:"followers"`
+	Following int `json:"following" db:"following"`
+}
@@ -1,7 +1,32 @@
 package models
 
+import "time"
+
+// Orders a friend list can be sorted in.
+const (
+	// FriendSortID sorts friends by user ID.
+	FriendSortID = "id"
+	// FriendSortFriendedAt sorts friends by friendship date, newest first.
+	FriendSortFriendedAt = "friended_at"
+)
+
 // Friend represents a user's friend.
 type Friend struct {
 	ID   int64  `json:"id" db:"id"`
 	Name string `json:"name" db:"name"`
+	// MutualFriendCount and MutualFriendIDs are only filled for friend-of-friend results.
+	MutualFriendCount int     `json:"mutual_friend_count,omitempty" db:"mutual_friend_count"`
+	MutualFriendIDs   []int64 `json:"mutual_friend_ids,omitempty" db:"-"`
+	// FriendedAt, CloseFriend and Nickname describe the friendship as the user sees it
+	// and are only filled for the user's own friend list.
+	FriendedAt  *time.Time `json:"friended_at,omitempty" db:"created_at"`
+	CloseFriend bool       `json:"close_friend,omitempty" db:"close_friend"`
+	Nickname    string     `json:"nickname,omitempty" db:"nickname"`
+}
+
+// FriendLinkUpdate holds the fields of a friendship a user can change on their side.
+// A nil field is left unchanged, and an empty Nickname removes the nickname.
+type FriendLinkUpdate struct {
+	CloseFriend *bool   `json:"close_friend"`
+	Nickname    *string `json:"nickname"`
 }
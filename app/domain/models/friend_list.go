@@ -0,0 +1,9 @@
+package models
+
+// FriendList represents a named group a user organizes their friends into, such as "Family".
+type FriendList struct {
+	ID          int64  `json:"id" db:"id"`
+	OwnerID     int64  `json:"owner_id" db:"owner_id"`
+	Name        string `json:"name" db:"name"`
+	MemberCount int    `json:"member_count" db:"member_count"`
+}
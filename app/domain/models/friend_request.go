@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// Statuses a friend request goes through.
+const (
+	FriendRequestPending  = "pending"
+	FriendRequestAccepted = "accepted"
+	FriendRequestDeclined = "declined"
+	FriendRequestCanceled = "canceled"
+	FriendRequestExpired  = "expired"
+	FriendRequestEnded    = "ended"
+)
+
+// FriendRequest represents a friend request and the transitions it went through.
+type FriendRequest struct {
+	ID          int64                `json:"id" db:"id"`
+	RequesterID int64                `json:"requester_id" db:"requester_id"`
+	RequestedID int64                `json:"requested_id" db:"requested_id"`
+	Status      string               `json:"status" db:"status"`
+	CreatedAt   time.Time            `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time            `json:"updated_at" db:"updated_at"`
+	Events      []FriendRequestEvent `json:"events" db:"-"`
+}
+
+// FriendRequestEvent represents a friend request entering a status.
+type FriendRequestEvent struct {
+	Status    string    `json:"status" db:"status"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// FriendRequestUser represents the other user of a friend request, along with the request itself.
+type FriendRequestUser struct {
+	ID        int64     `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name"`
+	RequestID int64     `json:"request_id" db:"request_id"`
+	Status    string    `json:"status" db:"status"`
+	Message   string    `json:"message" db:"message"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,35 @@
+package models
+
+// Kinds of inconsistency between friend_link, friend_requests, follow, friend_list_members and block_list.
+const (
+	// InconsistencyOneSidedLink is a friend_link row whose reverse row is missing.
+	InconsistencyOneSidedLink = "one_sided_link"
+	// InconsistencyBlockedLink is a friend_link row between users who have blocked each other.
+	InconsistencyBlockedLink = "blocked_link"
+	// InconsistencyAcceptedWithoutLink is an accepted friend request between users who are not linked.
+	InconsistencyAcceptedWithoutLink = "accepted_request_without_link"
+	// InconsistencyPendingBetweenFriends is a pending friend request between users who are already friends.
+	InconsistencyPendingBetweenFriends = "pending_request_between_friends"
+	// InconsistencyBlockedFollow is a follow row between users who have blocked each other.
+	InconsistencyBlockedFollow = "follow_between_blocked_users"
+	// InconsistencyListMemberNotFriend is a friend list member who is not a friend of the list owner.
+	InconsistencyListMemberNotFriend = "list_member_not_friend"
+)
+
+// Inconsistency represents a row breaking a friendship invariant.
+// RequestID is only set for inconsistencies found in friend_requests.
+type Inconsistency struct {
+	Kind      string `json:"kind"`
+	User1ID   int64  `json:"user1_id"`
+	User2ID   int64  `json:"user2_id"`
+	RequestID int64  `json:"request_id,omitempty"`
+}
+
+// FriendshipReport summarizes the inconsistencies found by a check.
+// Counts holds the number of inconsistencies of every kind, while Inconsistencies lists at most
+// the requested number of them per kind. Repaired is only set after a repair.
+type FriendshipReport struct {
+	Counts          map[string]int  `json:"counts"`
+	Inconsistencies []Inconsistency `json:"inconsistencies"`
+	Repaired        map[string]int  `json:"repaired,omitempty"`
+}
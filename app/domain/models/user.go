@@ -2,8 +2,58 @@
 
 package models
 
-// User represents a user.
+import "time"
+
+// Ways a user search can match the query against user names.
+const (
+	// UserSearchPrefix matches the names starting with the query.
+	UserSearchPrefix = "prefix"
+	// UserSearchSubstring matches the names containing the query anywhere.
+	UserSearchSubstring = "substring"
+)
+
+// User represents a user. Name is the unique handle, the other fields make up the profile.
 type User struct {
-	ID   int64  `json:"id" db:"id"`
-	Name string `json:"name" db:"name"`
+	ID          int64  `json:"id" db:"id"`
+	Name        string `json:"name" db:"name"`
+	DisplayName string `json:"display_name" db:"display_name"`
+	Bio         string `json:"bio" db:"bio"`
+	// Birthday is formatted as YYYY-MM-DD and empty when not set.
+	Birthday   string    `json:"birthday,omitempty" db:"birthday"`
+	Location   string    `json:"location" db:"location"`
+	AvatarPath string    `json:"avatar_path" db:"avatar_path"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Reasons a name is not available as a handle.
+const (
+	NameInvalid  = "invalid"
+	NameReserved = "reserved"
+	NameTaken    = "taken"
+)
+
+// NameAvailability tells whether a name can be taken as a handle. Name is the normalized form the handle would have,
+// and Reason is set when it is not available.
+type NameAvailability struct {
+	Name      string `json:"name"`
+	Available bool   `json:"available"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// UserBatch is the result of looking up several users at once.
+// MissingIDs lists the requested IDs no user was found for.
+type UserBatch struct {
+	Users      []User  `json:"users"`
+	MissingIDs []int64 `json:"missing_ids"`
+}
+
+// UserUpdate holds the profile fields of a user to change.
+// A nil field is left unchanged, and an empty Birthday removes the birthday.
+type UserUpdate struct {
+	DisplayName *string `json:"display_name"`
+	Bio         *string `json:"bio"`
+	Birthday    *string `json:"birthday"`
+	Location    *string `json:"location"`
+	AvatarPath  *string `json:"avatar_path"`
 }
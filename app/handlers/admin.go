@@ -0,0 +1,81 @@
+// handlers/admin.go
+package handlers
+
+import (
+	"minimal_sns_app/logutils"
+	"minimal_sns_app/repository"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// defaultInconsistencyLimit is the number of inconsistencies of every kind listed when limit is omitted.
+	defaultInconsistencyLimit = 100
+	// defaultRepairBatchSize is the number of rows repaired per transaction when batch_size is omitted.
+	defaultRepairBatchSize = 500
+)
+
+type AdminHandler struct {
+	InvariantRepo repository.InvariantRepository
+}
+
+func NewAdminHandler(InvariantRepo repository.InvariantRepository) *AdminHandler {
+	return &AdminHandler{InvariantRepo: InvariantRepo}
+}
+
+// RegisterRoutes registers the routes for admin operations
+func (h *AdminHandler) RegisterRoutes(e *echo.Echo) {
+	// bonus path ex: /admin/check_friendships?limit=100 response: 200 {"counts":{"one_sided_link":1,...},"inconsistencies":[{"kind":"one_sided_link","user1_id":1,"user2_id":2}]} or 500 "Failed to check friendships"
+	e.GET("/admin/check_friendships", h.CheckFriendships)
+
+	// bonus path ex: /admin/repair_friendships?batch_size=500 response: 200 {"counts":{"one_sided_link":0,...},"inconsistencies":[],"repaired":{"one_sided_link":1,...}} or 500 "Failed to repair friendships"
+	e.POST("/admin/repair_friendships", h.RepairFriendships)
+}
+
+// CheckFriendships handles GET requests to report the inconsistencies between friend links, friend requests and blocks
+func (h *AdminHandler) CheckFriendships(c echo.Context) error {
+	limit := defaultInconsistencyLimit
+	if limitParam := c.QueryParam("limit"); limitParam != "" {
+		var err error
+		limit, err = strconv.Atoi(limitParam)
+		if err != nil || limit <= 0 {
+			logutils.Error("Invalid limit")
+			return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid limit")
+		}
+	}
+
+	report, err := h.InvariantRepo.CheckFriendships(limit)
+	if err != nil {
+		return repositoryError(err, "Failed to check friendships")
+	}
+
+	return dataResponse(c, report)
+}
+
+// RepairFriendships handles POST requests to repair every inconsistency, then report what is left
+func (h *AdminHandler) RepairFriendships(c echo.Context) error {
+	batchSize := defaultRepairBatchSize
+	if batchSizeParam := c.QueryParam("batch_size"); batchSizeParam != "" {
+		var err error
+		batchSize, err = strconv.Atoi(batchSizeParam)
+		if err != nil || batchSize <= 0 {
+			logutils.Error("Invalid batch size")
+			return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid batch size")
+		}
+	}
+
+	repaired, err := h.InvariantRepo.RepairFriendships(batchSize)
+	if err != nil {
+		return repositoryError(err, "Failed to repair friendships")
+	}
+
+	report, err := h.InvariantRepo.CheckFriendships(defaultInconsistencyLimit)
+	if err != nil {
+		return repositoryError(err, "Failed to check friendships")
+	}
+	report.Repaired = repaired
+
+	return dataResponse(c, report)
+}
@@ -0,0 +1,201 @@
+// handlers/error_handler.go
+package handlers
+
+import (
+	"errors"
+	"minimal_sns_app/logutils"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	codeMethodNotAllowed = "method_not_allowed"
+	codeHTTPError        = "http_error"
+)
+
+// HTTPErrorHandler writes every error, including the ones raised by the router and recovered panics,
+// in the response envelope. The message is translated to the language preferred by Accept-Language.
+func HTTPErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	status, detail := errorDetail(err)
+	detail.Message = translateMessage(detail.Message, preferredLanguage(c.Request().Header.Get("Accept-Language")))
+	detail.RequestID = c.Response().Header().Get(echo.HeaderXRequestID)
+
+	if status >= http.StatusInternalServerError {
+		logutils.Error(err.Error())
+	}
+
+	var writeErr error
+	if c.Request().Method == http.MethodHead {
+		writeErr = c.NoContent(status)
+	} else {
+		writeErr = c.JSON(status, Response{Error: &detail})
+	}
+	if writeErr != nil {
+		logutils.Error(writeErr.Error())
+	}
+}
+
+// errorDetail works out the status and the error detail to respond with.
+func errorDetail(err error) (int, ErrorDetail) {
+	var he *echo.HTTPError
+	if !errors.As(err, &he) {
+		return http.StatusInternalServerError, ErrorDetail{Code: codeInternalError, Message: "Internal server error"}
+	}
+
+	switch message := he.Message.(type) {
+	case *ErrorDetail:
+		return he.Code, *message
+	case ErrorDetail:
+		return he.Code, message
+	}
+
+	// Errors raised by echo itself, such as an unknown route.
+	switch he.Code {
+	case http.StatusNotFound:
+		return he.Code, ErrorDetail{Code: codeNotFound, Message: "Not found"}
+	case http.StatusMethodNotAllowed:
+		return he.Code, ErrorDetail{Code: codeMethodNotAllowed, Message: "Method not allowed"}
+	case http.StatusBadRequest:
+		return he.Code, ErrorDetail{Code: codeInvalidParameter, Message: "Bad request"}
+	}
+	if he.Code >= http.StatusInternalServerError {
+		return he.Code, ErrorDetail{Code: codeInternalError, Message: "Internal server error"}
+	}
+	return he.Code, ErrorDetail{Code: codeHTTPError, Message: http.StatusText(he.Code)}
+}
+
+// Languages error messages can be written in.
+const (
+	languageEnglish  = "en"
+	languageJapanese = "ja"
+)
+
+// preferredLanguage picks the supported language with the highest weight in an Accept-Language header.
+// English is used when the header names no supported language.
+func preferredLanguage(acceptLanguage string) string {
+	language := languageEnglish
+	bestWeight := 0.0
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		weight := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+			weight = parsed
+		}
+
+		base, _, _ := strings.Cut(strings.ToLower(tag), "-")
+		if (base == languageEnglish || base == languageJapanese) && weight > bestWeight {
+			language = base
+			bestWeight = weight
+		}
+	}
+	return language
+}
+
+// translateMessage returns the message in the given language, or the English message when there is no translation.
+func translateMessage(message string, language string) string {
+	if language != languageJapanese {
+		return message
+	}
+	if translated, ok := japaneseMessages[message]; ok {
+		return translated
+	}
+	return message
+}
+
+// japaneseMessages translates the English error messages.
+var japaneseMessages = map[string]string{
+	"Internal server error":                       "サーバー内部でエラーが発生しました",
+	"Not found":                                   "見つかりません",
+	"Method not allowed":                          "許可されていないメソッドです",
+	"Bad request":                                 "不正なリクエストです",
+	"Already exists":                              "既に存在します",
+	"Already friends":                             "既に友達です",
+	"Blocked user":                                "ブロックされているユーザーです",
+	"Cannot target yourself":                      "自分自身を対象にはできません",
+	"Friend request is not pending":               "友達申請は既に処理されています",
+	"Friend request was declined recently":        "友達申請は最近拒否されたため、しばらく送信できません",
+	"Not friends":                                 "友達ではありません",
+	"Friend path not found":                       "友達のつながりが見つかりません",
+	"Invalid user id":                             "ユーザーIDが不正です",
+	"Invalid user ids":                            "ユーザーIDの一覧が不正です",
+	"Specify either id or name":                   "id と name のどちらか一方を指定してください",
+	"Invalid friend id":                           "友達のIDが不正です",
+	"Invalid requester id":                        "申請者のIDが不正です",
+	"Invalid requested id":                        "申請先のIDが不正です",
+	"Invalid block id":                            "ブロック対象のIDが不正です",
+	"Invalid other id":                            "相手のIDが不正です",
+	"Invalid target id":                           "対象のIDが不正です",
+	"Invalid name":                                "名前が不正です",
+	"Reserved name":                               "その名前は予約されているため使用できません",
+	"Invalid display name":                        "表示名が不正です",
+	"Invalid bio":                                 "自己紹介が不正です",
+	"Invalid birthday":                            "誕生日が不正です",
+	"Invalid location":                            "居住地が不正です",
+	"Invalid avatar path":                         "アバター画像のパスが不正です",
+	"Invalid limit":                               "件数の指定が不正です",
+	"Invalid page number":                         "ページ番号が不正です",
+	"Invalid request body":                        "リクエストボディが不正です",
+	"Invalid message":                             "メッセージが不正です",
+	"Invalid batch size":                          "バッチサイズの指定が不正です",
+	"Failed to check friendships":                 "友達関係の検査に失敗しました",
+	"Failed to repair friendships":                "友達関係の修復に失敗しました",
+	"Invalid list id":                             "リストのIDが不正です",
+	"Invalid status":                              "ステータスの指定が不正です",
+	"Invalid sort":                                "並び順の指定が不正です",
+	"Invalid nickname":                            "ニックネームが不正です",
+	"Nothing to update":                           "更新する項目がありません",
+	"Invalid query":                               "検索語が不正です",
+	"Invalid match":                               "一致方法の指定が不正です",
+	"Invalid cursor":                              "カーソルが不正です",
+	"Invalid depth":                               "深さの指定が不正です",
+	"Invalid max depth":                           "最大の深さの指定が不正です",
+	"Failed to send friend request":               "友達申請の送信に失敗しました",
+	"Failed to get friend requesters list":        "友達申請者一覧の取得に失敗しました",
+	"Failed to get friend requested list":         "友達申請先一覧の取得に失敗しました",
+	"Failed to get friend request history":        "友達申請の履歴の取得に失敗しました",
+	"Failed to accept friend request":             "友達申請の承認に失敗しました",
+	"Failed to decline friend request":            "友達申請の拒否に失敗しました",
+	"Failed to cancel friend request":             "友達申請の取り消しに失敗しました",
+	"Failed to get friends":                       "友達一覧の取得に失敗しました",
+	"Failed to get friends with paging":           "友達一覧の取得に失敗しました",
+	"Failed to get friends with cursor":           "友達一覧の取得に失敗しました",
+	"Failed to get friend of friends with paging": "友達の友達一覧の取得に失敗しました",
+	"Failed to get friend of friends with cursor": "友達の友達一覧の取得に失敗しました",
+	"Failed to get mutual friends":                "共通の友達の取得に失敗しました",
+	"Failed to get friend recommendations":        "おすすめの友達の取得に失敗しました",
+	"Failed to get friend path":                   "友達のつながりの取得に失敗しました",
+	"Failed to get friend network":                "友達のネットワークの取得に失敗しました",
+	"Failed to delete friend":                     "友達の削除に失敗しました",
+	"Failed to update friend":                     "友達の設定の更新に失敗しました",
+	"Failed to create friend list":                "友達リストの作成に失敗しました",
+	"Failed to get friend lists":                  "友達リスト一覧の取得に失敗しました",
+	"Failed to rename friend list":                "友達リストの名前の変更に失敗しました",
+	"Failed to delete friend list":                "友達リストの削除に失敗しました",
+	"Failed to add friend to list":                "友達リストへの追加に失敗しました",
+	"Failed to remove friend from list":           "友達リストからの削除に失敗しました",
+	"Failed to follow user":                       "フォローに失敗しました",
+	"Failed to unfollow user":                     "フォローの解除に失敗しました",
+	"Failed to get followers":                     "フォロワー一覧の取得に失敗しました",
+	"Failed to get followed users":                "フォロー中一覧の取得に失敗しました",
+	"Failed to get follow counts":                 "フォロー数の取得に失敗しました",
+	"Failed to get users":                         "ユーザーの取得に失敗しました",
+	"Failed to search users":                      "ユーザーの検索に失敗しました",
+	"Failed to check name":                        "名前の確認に失敗しました",
+	"Failed to deactivate user":                   "ユーザーの退会に失敗しました",
+	"Failed to restore user":                      "ユーザーの復元に失敗しました",
+	"Failed to update user":                       "ユーザー情報の更新に失敗しました",
+	"Failed to add to block list":                 "ブロックに失敗しました",
+	"Failed to get block list":                    "ブロック一覧の取得に失敗しました",
+	"Failed to remove from block list":            "ブロックの解除に失敗しました",
+}
@@ -0,0 +1,112 @@
+// handlers/follow.go
+package handlers
+
+import (
+	"minimal_sns_app/logutils"
+	"minimal_sns_app/repository"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+type FollowHandler struct {
+	FollowRepo repository.FollowRepository
+}
+
+func NewFollowHandler(FollowRepo repository.FollowRepository) *FollowHandler {
+	return &FollowHandler{FollowRepo: FollowRepo}
+}
+
+// RegisterRoutes registers the routes for follow operations
+func (h *FollowHandler) RegisterRoutes(e *echo.Echo) {
+	// bonus path ex: /follow?id=1&target_id=2 response: 200 "User followed" or 403 "Blocked user" or 404 "Not found" or 409 "Already exists" or 422 "Cannot target yourself" or 500 "Failed to follow user"
+	e.POST("/follow", h.Follow)
+
+	// bonus path ex: /unfollow?id=1&target_id=2 response: 200 "User unfollowed" or 404 "Not found" or 500 "Failed to unfollow user"
+	e.DELETE("/unfollow", h.Unfollow)
+
+	// bonus path ex: /get_follower_list?id=1&limit=10&cursor=djE6Mg response: 200 [{"id":3,"name":"charlie"}] with meta.next_cursor or 500 "Failed to get followers"
+	e.GET("/get_follower_list", h.GetFollowerList)
+
+	// bonus path ex: /get_following_list?id=1&limit=10&cursor=djE6Mg response: 200 [{"id":3,"name":"charlie"}] with meta.next_cursor or 500 "Failed to get followed users"
+	e.GET("/get_following_list", h.GetFollowingList)
+
+	// bonus path ex: /get_follow_count?id=1 response: 200 {"followers":120,"following":3} or 500 "Failed to get follow counts"
+	e.GET("/get_follow_count", h.GetFollowCount)
+}
+
+// Follow handles POST requests to follow a user
+func (h *FollowHandler) Follow(c echo.Context) error {
+	userIDParam := c.QueryParam("id")
+	userID, err := strconv.ParseInt(userIDParam, 10, 64)
+	if err != nil {
+		logutils.Error("Invalid user id")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid user id")
+	}
+
+	targetIDParam := c.QueryParam("target_id")
+	targetID, err := strconv.ParseInt(targetIDParam, 10, 64)
+	if err != nil {
+		logutils.Error("Invalid target id")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid target id")
+	}
+
+	err = h.FollowRepo.Follow(userID, targetID)
+	if err != nil {
+		return repositoryError(err, "Failed to follow user")
+	}
+
+	return messageResponse(c, "User followed")
+}
+
+// Unfollow handles DELETE requests to stop following a user
+func (h *FollowHandler) Unfollow(c echo.Context) error {
+	userIDParam := c.QueryParam("id")
+	userID, err := strconv.ParseInt(userIDParam, 10, 64)
+	if err != nil {
+		logutils.Error("Invalid user id")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid user id")
+	}
+
+	targetIDParam := c.QueryParam("target_id")
+	targetID, err := strconv.ParseInt(targetIDParam, 10, 64)
+	if err != nil {
+		logutils.Error("Invalid target id")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid target id")
+	}
+
+	err = h.FollowRepo.Unfollow(userID, targetID)
+	if err != nil {
+		return repositoryError(err, "Failed to unfollow user")
+	}
+
+	return messageResponse(c, "User unfollowed")
+}
+
+// GetFollowerList handles GET requests to retrieve the users following a user with cursor pagination
+func (h *FollowHandler) GetFollowerList(c echo.Context) error {
+	return listByCursor(c, h.FollowRepo.GetFollowersCursor, "Failed to get followers")
+}
+
+// GetFollowingList handles GET requests to retrieve the users a user follows with cursor pagination
+func (h *FollowHandler) GetFollowingList(c echo.Context) error {
+	return listByCursor(c, h.FollowRepo.GetFollowingCursor, "Failed to get followed users")
+}
+
+// GetFollowCount handles GET requests to retrieve the number of followers and followed users of a user
+func (h *FollowHandler) GetFollowCount(c echo.Context) error {
+	userIDParam := c.QueryParam("id")
+	userID, err := strconv.ParseInt(userIDParam, 10, 64)
+	if err != nil {
+		logutils.Error("Invalid user id")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid user id")
+	}
+
+	counts, err := h.FollowRepo.GetFollowCounts(userID)
+	if err != nil {
+		return repositoryError(err, "Failed to get follow counts")
+	}
+
+	return dataResponse(c, counts)
+}
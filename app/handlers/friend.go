@@ -2,15 +2,34 @@
 package handlers
 
 import (
-	"log"
+	"minimal_sns_app/domain/models"
 	"minimal_sns_app/logutils"
 	"minimal_sns_app/repository"
+	"minimal_sns_app/textutils"
 	"net/http"
 	"strconv"
 
 	"github.com/labstack/echo/v4"
 )
 
+const (
+	// defaultFriendPathDepth is used when /get_friend_path is called without max_depth.
+	defaultFriendPathDepth = 6
+	// maxFriendPathDepth caps max_depth so that a single request cannot walk the whole graph.
+	maxFriendPathDepth = 10
+	// maxFriendNetworkDepth caps the depth accepted by /get_friend_network.
+	maxFriendNetworkDepth = 4
+	// maxFriendRequestMessageLength is the number of characters a friend request message can hold.
+	maxFriendRequestMessageLength = 200
+	// maxFriendNicknameLength is the number of characters a friend's nickname can hold, as in friend_link.nickname.
+	maxFriendNicknameLength = 64
+)
+
+// friendRequestBody is the optional JSON body of /request_friend.
+type friendRequestBody struct {
+	Message string `json:"message"`
+}
+
 type FriendHandler struct {
 	FriendRepo repository.FriendRepository
 }
@@ -21,105 +40,185 @@ func NewFriendHandler(FriendRepo repository.FriendRepository) *FriendHandler {
 
 // RegisterRoutes registers the routes for friend operations
 func (h *FriendHandler) RegisterRoutes(e *echo.Echo) {
-	// bonus path ex: /request_friend?id=1&friend_id=2 response: 200 "Friend request sent" or 500 "Failed to send friend request"
+	// bonus path ex: /request_friend?id=1&friend_id=2 body: {"message":"hello"} (optional) response: 200 "Friend request sent" or 200 "Friend request accepted" when friend_id had already requested id or 400 "Invalid message" or 403 "Blocked user" or 404 "Not found" or 409 "Already friends" or 422 "Cannot target yourself" or 429 "Friend request was declined recently" or 500 "Failed to send friend request"
 	e.POST("/request_friend", h.RequestFriend)
 
-	// bonus path ex: /get_friend_requester_list?id=1 response: 200 [{"id":1,"name":"alice"}] or 500 "Failed to get friend requesters list"
+	// bonus path ex: /get_friend_requester_list?id=1&status=pending response: 200 [{"id":1,"name":"alice","request_id":3,"status":"pending","message":"hello","created_at":"2024-01-01T00:00:00Z"}] or 400 "Invalid status" or 500 "Failed to get friend requesters list"
 	e.GET("/get_friend_requester_list", h.GetFriendRequesterList)
 
-	// bonus path ex: /get_friend_requested_list?id=1 response: 200 [{"id":1,"name":"alice"}] or 500 "Failed to get friend requested list"
+	// bonus path ex: /get_friend_requester_list_cursor?id=1&limit=10&cursor=djE6Mg response: 200 [{"id":3,"name":"charlie"}] with meta.next_cursor or 500 "Failed to get friend requesters list"
+	e.GET("/get_friend_requester_list_cursor", h.GetFriendRequesterListCursor)
+
+	// bonus path ex: /get_friend_requested_list?id=1&status=pending response: 200 [{"id":1,"name":"alice","request_id":3,"status":"pending","message":"hello","created_at":"2024-01-01T00:00:00Z"}] or 400 "Invalid status" or 500 "Failed to get friend requested list"
 	e.GET("/get_friend_requested_list", h.GetFriendRequestedList)
 
-	// bonus path ex: /accept_friend?id=1&friend_id=2 response: 200 "Friend request accepted" or 500 "Failed to accept friend request"
+	// bonus path ex: /get_friend_requested_list_cursor?id=1&limit=10&cursor=djE6Mg response: 200 [{"id":3,"name":"charlie"}] with meta.next_cursor or 500 "Failed to get friend requested list"
+	e.GET("/get_friend_requested_list_cursor", h.GetFriendRequestedListCursor)
+
+	// bonus path ex: /accept_friend?id=1&friend_id=2 response: 200 "Friend request accepted" or 404 "Not found" or 409 "Friend request is not pending" or 500 "Failed to accept friend request"
 	e.POST("/accept_friend", h.AcceptFriend)
 
-	// bonus path ex: /decline_friend?id=1&friend_id=2 response: 200 "Friend request declined" or 500 "Failed to decline friend request"
+	// bonus path ex: /decline_friend?id=1&friend_id=2 response: 200 "Friend request declined" or 404 "Not found" or 409 "Friend request is not pending" or 500 "Failed to decline friend request"
 	e.POST("/decline_friend", h.DeclineFriend)
 
-	// mandatory path ex: /get_friend_list?id=1 response: 200 [{"id":1,"name":"alice"}] or 500 "Failed to get friends"
+	// bonus path ex: /cancel_friend_request?id=1&friend_id=2 response: 200 "Friend request canceled" or 404 "Not found" or 409 "Friend request is not pending" or 500 "Failed to cancel friend request"
+	e.POST("/cancel_friend_request", h.CancelFriendRequest)
+
+	// bonus path ex: /get_friend_request_history?id=1&friend_id=2 response: 200 [{"id":1,"requester_id":1,"requested_id":2,"status":"declined","created_at":"...","updated_at":"...","events":[{"status":"pending","created_at":"..."},{"status":"declined","created_at":"..."}]}] or 404 "Not found" or 500 "Failed to get friend request history"
+	e.GET("/get_friend_request_history", h.GetFriendRequestHistory)
+
+	// mandatory path ex: /get_friend_list?id=1 response: 200 [{"id":1,"name":"alice","friended_at":"2024-01-01T00:00:00Z","close_friend":true,"nickname":"ali"}] or 500 "Failed to get friends"
+	// list_id (optional) restricts the friends to one of the user's friend lists, 404 "Not found" when the user has no such list
+	// sort (optional) is id (default) or friended_at for the newest friendships first, 400 "Invalid sort" otherwise
 	e.GET("/get_friend_list", h.GetFriendList)
 
-	// bonus path ex: /get_friend_list_paging?id=1&limit=10&page=1 response: 200 [{"id":1,"name":"alice"}] or 500 "Failed to get friends with paging"
+	// bonus path ex: /get_friend_list_paging?id=1&limit=10&page=1&sort=friended_at response: 200 [{"id":1,"name":"alice","friended_at":"2024-01-01T00:00:00Z"}] or 400 "Invalid sort" or 500 "Failed to get friends with paging"
 	e.GET("/get_friend_list_paging", h.GetFriendListPaging)
 
+	// bonus path ex: /friend?id=1&friend_id=2 body: {"close_friend":true,"nickname":"ali"} response: 200 "Friend updated" or 400 "Invalid nickname" or 400 "Nothing to update" or 404 "Not found" or 500 "Failed to update friend"
+	// Only id's side of the friendship changes, and an empty nickname removes it
+	e.PATCH("/friend", h.UpdateFriend)
+
+	// bonus path ex: /get_friend_list_cursor?id=1&limit=10&cursor=djE6Mg response: 200 [{"id":3,"name":"charlie"}] with meta.next_cursor or 500 "Failed to get friends with cursor"
+	e.GET("/get_friend_list_cursor", h.GetFriendListCursor)
+
 	// mandatory path ex: /get_friend_of_friend_list?id=1 response: 200 [{"id":1,"name":"alice"}] or 500 "Failed to get friends"
 	e.GET("/get_friend_of_friend_list", h.GetFriendOfFriendList)
 
 	// mandatory path ex: /get_friend_of_friend_list_paging?id=1&limit=10&page=1 response: 200 [{"id":1,"name":"alice"}] or 500 "Failed to get friends with paging"
 	e.GET("/get_friend_of_friend_list_paging", h.GetFriendOfFriendListPaging)
 
-	// bonus path ex: /delete_friend?id=1&friend_id=2 response: 200 "success" or 500 "Failed to delete friend"
+	// bonus path ex: /get_friend_of_friend_list_cursor?id=1&limit=10&cursor=djE6Mg response: 200 [{"id":3,"name":"charlie"}] with meta.next_cursor or 500 "Failed to get friend of friends with cursor"
+	e.GET("/get_friend_of_friend_list_cursor", h.GetFriendOfFriendListCursor)
+
+	// bonus path ex: /get_mutual_friend_list?id=1&other_id=2 response: 200 [{"id":3,"name":"charlie"}] or 404 "Not found" or 500 "Failed to get mutual friends"
+	e.GET("/get_mutual_friend_list", h.GetMutualFriendList)
+
+	// bonus path ex: /get_friend_recommendation_list?id=1&limit=10&page=1 response: 200 [{"id":4,"name":"david","mutual_friend_count":2,"mutual_friend_ids":[2,3]}] or 500 "Failed to get friend recommendations"
+	e.GET("/get_friend_recommendation_list", h.GetFriendRecommendationList)
+
+	// bonus path ex: /get_friend_path?id=1&target_id=4&max_depth=6 response: 200 [{"id":1,"name":"alice"},{"id":2,"name":"bob"},{"id":4,"name":"david"}] or 404 "Friend path not found"
+	e.GET("/get_friend_path", h.GetFriendPath)
+
+	// bonus path ex: /get_friend_network?id=1&depth=3 response: 200 [{"id":5,"name":"eve"}] or 500 "Failed to get friend network"
+	e.GET("/get_friend_network", h.GetFriendNetwork)
+
+	// bonus path ex: /delete_friend?id=1&friend_id=2 response: 200 "success" or 404 "Not found" or 500 "Failed to delete friend"
 	e.DELETE("/delete_friend", h.DeleteFriend)
 
-	// bonus path ex: /add_block?id=1&block_id=2 response: 200 "User blocked" or 500 "Failed to add to block list"
+	// bonus path ex: /add_block?id=1&block_id=2 response: 200 "User blocked" or 409 "Already exists" or 422 "Cannot target yourself" or 500 "Failed to add to block list"
 	e.POST("/add_block", h.AddBlock)
 
 	// bonus path ex: /get_block_list?id=1 response: 200 [{"id":1,"name":"alice"}] or 500 "Failed to get block list"
 	e.GET("/get_block_list", h.GetBlockList)
 
+	// bonus path ex: /get_block_list_cursor?id=1&limit=10&cursor=djE6Mg response: 200 [{"id":3,"name":"charlie"}] with meta.next_cursor or 500 "Failed to get block list"
+	e.GET("/get_block_list_cursor", h.GetBlockListCursor)
+
 	// bonus path ex: /delete_block?id=1&block_id=2 response: 200 "User unblocked" or 500 "Failed to remove from block list"
 	e.DELETE("/delete_block", h.DeleteBlock)
 }
 
-// RequestFriend handles POST requests to send a friend request
+// RequestFriend handles POST requests to send a friend request, or to accept the crossed one when it exists
 func (h *FriendHandler) RequestFriend(c echo.Context) error {
 	requesterIDParam := c.QueryParam("id")
 	requesterID, err := strconv.ParseInt(requesterIDParam, 10, 64)
 	if err != nil {
 		logutils.Error("Invalid requester id")
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid requester id")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid requester id")
 	}
 
 	requestedIDParam := c.QueryParam("friend_id")
 	requestedID, err := strconv.ParseInt(requestedIDParam, 10, 64)
 	if err != nil {
 		logutils.Error("Invalid requested id")
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid requested id")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid requested id")
 	}
 
-	err = h.FriendRepo.RequestFriend(requesterID, requestedID)
+	var body friendRequestBody
+	if err := c.Bind(&body); err != nil {
+		logutils.Error("Invalid request body")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid request body")
+	}
+	message, ok := textutils.FreeText(body.Message, maxFriendRequestMessageLength, true)
+	if !ok {
+		logutils.Error("Invalid message")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid message")
+	}
+
+	accepted, err := h.FriendRepo.RequestFriend(requesterID, requestedID, message)
 	if err != nil {
-		logutils.Error("Failed to send friend request")
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to send friend request")
+		return repositoryError(err, "Failed to send friend request")
 	}
 
-	return c.String(http.StatusOK, "Friend request sent")
+	// The other user had already requested the requester, so both requests were accepted
+	if accepted {
+		return messageResponse(c, "Friend request accepted")
+	}
+	return messageResponse(c, "Friend request sent")
 }
 
-// GetFriendRequesterList handles GET requests to retrieve the list of users who have sent a friend request
+// GetFriendRequesterList handles GET requests to retrieve the list of users who have sent a friend request, pending ones unless status says otherwise
 func (h *FriendHandler) GetFriendRequesterList(c echo.Context) error {
 	userIDParam := c.QueryParam("id")
 	userID, err := strconv.ParseInt(userIDParam, 10, 64)
 	if err != nil {
-		logutils.Error("Invalid id")
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid id")
+		logutils.Error("Invalid user id")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid user id")
+	}
+
+	status, err := friendRequestStatus(c.QueryParam("status"))
+	if err != nil {
+		return err
 	}
 
-	requesters, err := h.FriendRepo.GetFriendRequesterList(userID)
+	requesters, err := h.FriendRepo.GetFriendRequesterList(userID, status)
 	if err != nil {
-		logutils.Error("Failed to get friend requesters list")
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get friend requesters list")
+		return repositoryError(err, "Failed to get friend requesters list")
+	}
+	if requesters == nil {
+		requesters = []models.FriendRequestUser{}
 	}
 
-	return c.JSON(http.StatusOK, requesters)
+	return collectionResponse(c, requesters, len(requesters))
 }
 
-// GetFriendRequestedList handles GET requests to retrieve the list of users to whom the user has sent a friend request
+// GetFriendRequestedList handles GET requests to retrieve the list of users to whom the user has sent a friend request, pending ones unless status says otherwise
 func (h *FriendHandler) GetFriendRequestedList(c echo.Context) error {
 	userIDParam := c.QueryParam("id")
 	userID, err := strconv.ParseInt(userIDParam, 10, 64)
 	if err != nil {
-		logutils.Error("Invalid id")
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid id")
+		logutils.Error("Invalid user id")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid user id")
 	}
 
-	requesteds, err := h.FriendRepo.GetFriendRequestedList(userID)
+	status, err := friendRequestStatus(c.QueryParam("status"))
 	if err != nil {
-		logutils.Error("Failed to get friend requested list")
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get friend requested list")
+		return err
 	}
 
-	return c.JSON(http.StatusOK, requesteds)
+	requesteds, err := h.FriendRepo.GetFriendRequestedList(userID, status)
+	if err != nil {
+		return repositoryError(err, "Failed to get friend requested list")
+	}
+	if requesteds == nil {
+		requesteds = []models.FriendRequestUser{}
+	}
+
+	return collectionResponse(c, requesteds, len(requesteds))
+}
+
+// friendRequestStatus validates the status filter of the friend request lists, which defaults to pending.
+func friendRequestStatus(status string) (string, error) {
+	switch status {
+	case "":
+		return models.FriendRequestPending, nil
+	case models.FriendRequestPending, models.FriendRequestAccepted, models.FriendRequestDeclined,
+		models.FriendRequestCanceled, models.FriendRequestExpired, models.FriendRequestEnded:
+		return status, nil
+	}
+	logutils.Error("Invalid status")
+	return "", newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid status")
 }
 
 // AcceptFriend handles POST requests to accept a friend request
@@ -127,24 +226,23 @@ func (h *FriendHandler) AcceptFriend(c echo.Context) error {
 	userIDParam := c.QueryParam("id")
 	userID, err := strconv.ParseInt(userIDParam, 10, 64)
 	if err != nil {
-		logutils.Error("Invalid id")
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid id")
+		logutils.Error("Invalid user id")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid user id")
 	}
 
 	friendIDParam := c.QueryParam("friend_id")
 	friendID, err := strconv.ParseInt(friendIDParam, 10, 64)
 	if err != nil {
 		logutils.Error("Invalid friend id")
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid friend id")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid friend id")
 	}
 
 	err = h.FriendRepo.AcceptFriend(userID, friendID)
 	if err != nil {
-		logutils.Error("Failed to accept friend request")
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to accept friend request")
+		return repositoryError(err, "Failed to accept friend request")
 	}
 
-	return c.String(http.StatusOK, "Friend request accepted")
+	return messageResponse(c, "Friend request accepted")
 }
 
 // DeclineFriend handles POST requests to decline a friend request
@@ -152,24 +250,74 @@ func (h *FriendHandler) DeclineFriend(c echo.Context) error {
 	userIDParam := c.QueryParam("id")
 	userID, err := strconv.ParseInt(userIDParam, 10, 64)
 	if err != nil {
-		logutils.Error("Invalid id")
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid id")
+		logutils.Error("Invalid user id")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid user id")
 	}
 
 	friendIDParam := c.QueryParam("friend_id")
 	friendID, err := strconv.ParseInt(friendIDParam, 10, 64)
 	if err != nil {
 		logutils.Error("Invalid friend id")
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid friend id")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid friend id")
 	}
 
 	err = h.FriendRepo.DeclineFriend(userID, friendID)
 	if err != nil {
-		logutils.Error("Failed to decline friend request")
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to decline friend request")
+		return repositoryError(err, "Failed to decline friend request")
+	}
+
+	return messageResponse(c, "Friend request declined")
+}
+
+// CancelFriendRequest handles POST requests from the requester to withdraw a pending friend request
+func (h *FriendHandler) CancelFriendRequest(c echo.Context) error {
+	userIDParam := c.QueryParam("id")
+	userID, err := strconv.ParseInt(userIDParam, 10, 64)
+	if err != nil {
+		logutils.Error("Invalid user id")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid user id")
+	}
+
+	friendIDParam := c.QueryParam("friend_id")
+	friendID, err := strconv.ParseInt(friendIDParam, 10, 64)
+	if err != nil {
+		logutils.Error("Invalid friend id")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid friend id")
+	}
+
+	err = h.FriendRepo.CancelFriendRequest(userID, friendID)
+	if err != nil {
+		return repositoryError(err, "Failed to cancel friend request")
+	}
+
+	return messageResponse(c, "Friend request canceled")
+}
+
+// GetFriendRequestHistory handles GET requests to retrieve the friend requests sent between two users with their transitions
+func (h *FriendHandler) GetFriendRequestHistory(c echo.Context) error {
+	userIDParam := c.QueryParam("id")
+	userID, err := strconv.ParseInt(userIDParam, 10, 64)
+	if err != nil {
+		logutils.Error("Invalid user id")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid user id")
+	}
+
+	friendIDParam := c.QueryParam("friend_id")
+	friendID, err := strconv.ParseInt(friendIDParam, 10, 64)
+	if err != nil {
+		logutils.Error("Invalid friend id")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid friend id")
+	}
+
+	requests, err := h.FriendRepo.GetFriendRequestHistory(userID, friendID)
+	if err != nil {
+		return repositoryError(err, "Failed to get friend request history")
+	}
+	if requests == nil {
+		requests = []models.FriendRequest{}
 	}
 
-	return c.String(http.StatusOK, "Friend request declined")
+	return collectionResponse(c, requests, len(requests))
 }
 
 // GetFriendList handles GET requests to retrieve a user's friend list
@@ -177,17 +325,88 @@ func (h *FriendHandler) GetFriendList(c echo.Context) error {
 	userIDParam := c.QueryParam("id")
 	userID, err := strconv.ParseInt(userIDParam, 10, 64)
 	if err != nil {
-		logutils.Error("Invalid id")
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid id")
+		logutils.Error("Invalid user id")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid user id")
+	}
+
+	sort, err := friendSort(c.QueryParam("sort"))
+	if err != nil {
+		return err
+	}
+
+	// Only the friends in one of the user's friend lists when list_id is given
+	var friends []models.Friend
+	if listIDParam := c.QueryParam("list_id"); listIDParam != "" {
+		listID, err := strconv.ParseInt(listIDParam, 10, 64)
+		if err != nil {
+			logutils.Error("Invalid list id")
+			return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid list id")
+		}
+		friends, err = h.FriendRepo.GetFriendsInList(userID, listID, sort)
+		if err != nil {
+			return repositoryError(err, "Failed to get friends")
+		}
+	} else {
+		friends, err = h.FriendRepo.GetFriends(userID, sort)
+		if err != nil {
+			return repositoryError(err, "Failed to get friends")
+		}
+	}
+
+	return listResponse(c, friends)
+}
+
+// friendSort validates the sort order of the friend lists, which defaults to the user ID.
+func friendSort(sort string) (string, error) {
+	switch sort {
+	case "":
+		return models.FriendSortID, nil
+	case models.FriendSortID, models.FriendSortFriendedAt:
+		return sort, nil
+	}
+	logutils.Error("Invalid sort")
+	return "", newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid sort")
+}
+
+// UpdateFriend handles PATCH requests to change the close friend flag or the nickname a user gives a friend
+func (h *FriendHandler) UpdateFriend(c echo.Context) error {
+	userIDParam := c.QueryParam("id")
+	userID, err := strconv.ParseInt(userIDParam, 10, 64)
+	if err != nil {
+		logutils.Error("Invalid user id")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid user id")
 	}
 
-	friends, err := h.FriendRepo.GetFriends(userID)
+	friendIDParam := c.QueryParam("friend_id")
+	friendID, err := strconv.ParseInt(friendIDParam, 10, 64)
 	if err != nil {
-		logutils.Error("Failed to get friends")
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get friends")
+		logutils.Error("Invalid friend id")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid friend id")
+	}
+
+	var update models.FriendLinkUpdate
+	if err := c.Bind(&update); err != nil {
+		logutils.Error("Invalid request body")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid request body")
+	}
+	if update.CloseFriend == nil && update.Nickname == nil {
+		logutils.Error("Nothing to update")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Nothing to update")
+	}
+	if update.Nickname != nil {
+		nickname, ok := textutils.FreeText(*update.Nickname, maxFriendNicknameLength, false)
+		if !ok {
+			logutils.Error("Invalid nickname")
+			return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid nickname")
+		}
+		update.Nickname = &nickname
 	}
 
-	return c.JSON(http.StatusOK, friends)
+	if err := h.FriendRepo.UpdateFriendLink(userID, friendID, update); err != nil {
+		return repositoryError(err, "Failed to update friend")
+	}
+
+	return messageResponse(c, "Friend updated")
 }
 
 // GetFriendOfFriendList handles GET requests to retrieve a user's friend list
@@ -195,43 +414,41 @@ func (h *FriendHandler) GetFriendOfFriendList(c echo.Context) error {
 	userIDParam := c.QueryParam("id")
 	userID, err := strconv.ParseInt(userIDParam, 10, 64)
 	if err != nil {
-		logutils.Error("Invalid id")
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid id")
+		logutils.Error("Invalid user id")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid user id")
 	}
 
 	friends, err := h.FriendRepo.GetFriendOfFriendList(userID)
 	if err != nil {
-		logutils.Error("Failed to get friends")
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get friends")
+		return repositoryError(err, "Failed to get friends")
 	}
 
-	return c.JSON(http.StatusOK, friends)
+	return listResponse(c, friends)
 }
 
-// DeleteFriend handles DELETE requests to delete a friend
+// DeleteFriend handles DELETE requests to end a friendship for both users
 func (h *FriendHandler) DeleteFriend(c echo.Context) error {
 	userIDParam := c.QueryParam("id")
 	userID, err := strconv.ParseInt(userIDParam, 10, 64)
 	if err != nil {
-		logutils.Error("Invalid id")
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid id")
+		logutils.Error("Invalid user id")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid user id")
 	}
 
 	friendIDParam := c.QueryParam("friend_id")
 	friendID, err := strconv.ParseInt(friendIDParam, 10, 64)
 	if err != nil {
-		logutils.Error("Invalid friend_id")
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid friend_id")
+		logutils.Error("Invalid friend id")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid friend id")
 	}
 
 	err = h.FriendRepo.DeleteFriend(userID, friendID)
 	if err != nil {
-		logutils.Error("Failed to delete friend")
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete friend")
+		return repositoryError(err, "Failed to delete friend")
 	}
 
 	// successを返す
-	return c.String(http.StatusOK, "success")
+	return messageResponse(c, "success")
 }
 
 // GetFriendListPaging handles GET requests to retrieve a user's friend list with pagination
@@ -240,66 +457,193 @@ func (h *FriendHandler) GetFriendListPaging(c echo.Context) error {
 	userID, err := strconv.ParseInt(userIDParam, 10, 64)
 	if err != nil {
 		logutils.Error("Invalid user id")
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid user id")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid user id")
+	}
+
+	limit, err := pageLimit(c)
+	if err != nil {
+		return err
 	}
 
-	limitParam := c.QueryParam("limit")
-	limit, err := strconv.Atoi(limitParam)
-	if err != nil || limit <= 0 {
-		logutils.Error("Invalid limit")
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid limit")
+	page, err := pageNumber(c)
+	if err != nil {
+		return err
 	}
 
-	pageParam := c.QueryParam("page")
-	page, err := strconv.Atoi(pageParam)
-	if err != nil || page <= 0 {
-		logutils.Error("Invalid page number")
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid page number")
+	sort, err := friendSort(c.QueryParam("sort"))
+	if err != nil {
+		return err
 	}
 
-	friends, err := h.FriendRepo.GetFriendsPaging(userID, limit, (page-1)*limit)
+	friends, total, err := h.FriendRepo.GetFriendsPaging(userID, sort, limit, (page-1)*limit)
 	if err != nil {
-		logutils.Error("Failed to get friends with paging")
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get friends with paging")
+		return repositoryError(err, "Failed to get friends with paging")
 	}
 
-	return c.JSON(http.StatusOK, friends)
+	return pageResponse(c, friends, Meta{Total: &total, Limit: limit, Page: page})
 }
 
 // GetFriendOfFriendListPaging handles GET requests to retrieve a user's friend of friends list with pagination
 func (h *FriendHandler) GetFriendOfFriendListPaging(c echo.Context) error {
 	userIDParam := c.QueryParam("id")
-	logutils.Info("userId: " + userIDParam)
 	userID, err := strconv.ParseInt(userIDParam, 10, 64)
 	if err != nil {
 		logutils.Error("Invalid user id")
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid user id")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid user id")
+	}
+
+	limit, err := pageLimit(c)
+	if err != nil {
+		return err
+	}
+
+	page, err := pageNumber(c)
+	if err != nil {
+		return err
+	}
+
+	friends, total, err := h.FriendRepo.GetFriendOfFriendListPaging(userID, limit, (page-1)*limit)
+	if err != nil {
+		return repositoryError(err, "Failed to get friend of friends with paging")
+	}
+
+	return pageResponse(c, friends, Meta{Total: &total, Limit: limit, Page: page})
+}
+
+// GetMutualFriendList handles GET requests to retrieve the friends two users have in common
+func (h *FriendHandler) GetMutualFriendList(c echo.Context) error {
+	userIDParam := c.QueryParam("id")
+	userID, err := strconv.ParseInt(userIDParam, 10, 64)
+	if err != nil {
+		logutils.Error("Invalid user id")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid user id")
+	}
+
+	otherIDParam := c.QueryParam("other_id")
+	otherID, err := strconv.ParseInt(otherIDParam, 10, 64)
+	if err != nil {
+		logutils.Error("Invalid other id")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid other id")
+	}
+
+	friends, err := h.FriendRepo.GetMutualFriends(userID, otherID)
+	if err != nil {
+		return repositoryError(err, "Failed to get mutual friends")
+	}
+
+	return listResponse(c, friends)
+}
+
+// GetFriendRecommendationList handles GET requests to retrieve friend suggestions ranked by mutual friends
+func (h *FriendHandler) GetFriendRecommendationList(c echo.Context) error {
+	userIDParam := c.QueryParam("id")
+	userID, err := strconv.ParseInt(userIDParam, 10, 64)
+	if err != nil {
+		logutils.Error("Invalid user id")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid user id")
+	}
+
+	limit, err := pageLimit(c)
+	if err != nil {
+		return err
+	}
+
+	page, err := pageNumber(c)
+	if err != nil {
+		return err
+	}
+
+	friends, total, err := h.FriendRepo.GetFriendRecommendations(userID, limit, (page-1)*limit)
+	if err != nil {
+		return repositoryError(err, "Failed to get friend recommendations")
+	}
+
+	return pageResponse(c, friends, Meta{Total: &total, Limit: limit, Page: page})
+}
+
+// GetFriendPath handles GET requests to retrieve the shortest chain of friends between two users
+func (h *FriendHandler) GetFriendPath(c echo.Context) error {
+	userIDParam := c.QueryParam("id")
+	userID, err := strconv.ParseInt(userIDParam, 10, 64)
+	if err != nil {
+		logutils.Error("Invalid user id")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid user id")
+	}
+
+	targetIDParam := c.QueryParam("target_id")
+	targetID, err := strconv.ParseInt(targetIDParam, 10, 64)
+	if err != nil {
+		logutils.Error("Invalid target id")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid target id")
 	}
 
-	limitParam := c.QueryParam("limit")
-	logutils.Info("limit: " + limitParam)
-	limit, err := strconv.Atoi(limitParam)
-	if err != nil || limit <= 0 {
-		logutils.Error("Invalid limit")
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid limit")
+	maxDepth := defaultFriendPathDepth
+	if maxDepthParam := c.QueryParam("max_depth"); maxDepthParam != "" {
+		maxDepth, err = strconv.Atoi(maxDepthParam)
+		if err != nil || maxDepth <= 0 || maxDepth > maxFriendPathDepth {
+			logutils.Error("Invalid max depth")
+			return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid max depth")
+		}
 	}
 
-	pageParam := c.QueryParam("page")
-	logutils.Info("page: " + pageParam)
-	page, err := strconv.Atoi(pageParam)
-	if err != nil || page <= 0 {
-		logutils.Error("Invalid page number")
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid page number")
+	path, err := h.FriendRepo.GetFriendPath(userID, targetID, maxDepth)
+	if err != nil {
+		return repositoryError(err, "Failed to get friend path")
+	}
+	if path == nil {
+		return newHTTPError(http.StatusNotFound, codeNotFound, "Friend path not found")
+	}
+
+	return listResponse(c, path)
+}
+
+// GetFriendNetwork handles GET requests to retrieve the users exactly depth hops away from a user
+func (h *FriendHandler) GetFriendNetwork(c echo.Context) error {
+	userIDParam := c.QueryParam("id")
+	userID, err := strconv.ParseInt(userIDParam, 10, 64)
+	if err != nil {
+		logutils.Error("Invalid user id")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid user id")
+	}
+
+	depthParam := c.QueryParam("depth")
+	depth, err := strconv.Atoi(depthParam)
+	if err != nil || depth <= 0 || depth > maxFriendNetworkDepth {
+		logutils.Error("Invalid depth")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid depth")
 	}
 
-	friends, err := h.FriendRepo.GetFriendOfFriendListPaging(userID, limit, (page-1)*limit)
+	friends, err := h.FriendRepo.GetFriendNetwork(userID, depth)
 	if err != nil {
-		logutils.Error("Failed to get friend of friends with paging")
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get friend of friends with paging")
+		return repositoryError(err, "Failed to get friend network")
 	}
-	log.Printf("friends: %v", friends)
 
-	return c.JSON(http.StatusOK, friends)
+	return listResponse(c, friends)
+}
+
+// GetFriendRequesterListCursor handles GET requests to retrieve the list of users who have sent a friend request with cursor pagination
+func (h *FriendHandler) GetFriendRequesterListCursor(c echo.Context) error {
+	return listByCursor(c, h.FriendRepo.GetFriendRequesterListCursor, "Failed to get friend requesters list")
+}
+
+// GetFriendRequestedListCursor handles GET requests to retrieve the list of users to whom the user has sent a friend request with cursor pagination
+func (h *FriendHandler) GetFriendRequestedListCursor(c echo.Context) error {
+	return listByCursor(c, h.FriendRepo.GetFriendRequestedListCursor, "Failed to get friend requested list")
+}
+
+// GetFriendListCursor handles GET requests to retrieve a user's friend list with cursor pagination
+func (h *FriendHandler) GetFriendListCursor(c echo.Context) error {
+	return listByCursor(c, h.FriendRepo.GetFriendsCursor, "Failed to get friends with cursor")
+}
+
+// GetFriendOfFriendListCursor handles GET requests to retrieve a user's friend of friends list with cursor pagination
+func (h *FriendHandler) GetFriendOfFriendListCursor(c echo.Context) error {
+	return listByCursor(c, h.FriendRepo.GetFriendOfFriendListCursor, "Failed to get friend of friends with cursor")
+}
+
+// GetBlockListCursor handles GET requests to retrieve a user's block list with cursor pagination
+func (h *FriendHandler) GetBlockListCursor(c echo.Context) error {
+	return listByCursor(c, h.FriendRepo.GetBlockListCursor, "Failed to get block list")
 }
 
 // AddBlock handles POST requests to block a user
@@ -308,23 +652,22 @@ func (h *FriendHandler) AddBlock(c echo.Context) error {
 	userID, err := strconv.ParseInt(userIDParam, 10, 64)
 	if err != nil {
 		logutils.Error("Invalid user id")
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid user id")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid user id")
 	}
 
 	blockIDParam := c.QueryParam("block_id")
 	blockID, err := strconv.ParseInt(blockIDParam, 10, 64)
 	if err != nil {
 		logutils.Error("Invalid block id")
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid block id")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid block id")
 	}
 
 	err = h.FriendRepo.AddBlock(userID, blockID)
 	if err != nil {
-		logutils.Error("Failed to add to block list")
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to add to block list")
+		return repositoryError(err, "Failed to add to block list")
 	}
 
-	return c.String(http.StatusOK, "User blocked")
+	return messageResponse(c, "User blocked")
 }
 
 // GetBlockList handles GET requests to retrieve a user's block list
@@ -333,16 +676,15 @@ func (h *FriendHandler) GetBlockList(c echo.Context) error {
 	userID, err := strconv.ParseInt(userIDParam, 10, 64)
 	if err != nil {
 		logutils.Error("Invalid user id")
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid user id")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid user id")
 	}
 
 	blocks, err := h.FriendRepo.GetBlockList(userID)
 	if err != nil {
-		logutils.Error("Failed to get block list")
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to get block list")
+		return repositoryError(err, "Failed to get block list")
 	}
 
-	return c.JSON(http.StatusOK, blocks)
+	return listResponse(c, blocks)
 }
 
 // DeleteBlock handles DELETE requests to unblock a user
@@ -351,21 +693,20 @@ func (h *FriendHandler) DeleteBlock(c echo.Context) error {
 	userID, err := strconv.ParseInt(userIDParam, 10, 64)
 	if err != nil {
 		logutils.Error("Invalid user id")
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid user id")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid user id")
 	}
 
 	blockIDParam := c.QueryParam("block_id")
 	blockID, err := strconv.ParseInt(blockIDParam, 10, 64)
 	if err != nil {
 		logutils.Error("Invalid block id")
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid block id")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid block id")
 	}
 
 	err = h.FriendRepo.DeleteBlock(userID, blockID)
 	if err != nil {
-		logutils.Error("Failed to remove from block list")
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to remove from block list")
+		return repositoryError(err, "Failed to remove from block list")
 	}
 
-	return c.String(http.StatusOK, "User unblocked")
+	return messageResponse(c, "User unblocked")
 }
@@ -0,0 +1,198 @@
+// handlers/friend_list.go
+package handlers
+
+import (
+	"minimal_sns_app/domain/models"
+	"minimal_sns_app/logutils"
+	"minimal_sns_app/repository"
+	"minimal_sns_app/textutils"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+// maxFriendListNameLength is the number of characters a friend list name can hold, as in friend_lists.name.
+const maxFriendListNameLength = 64
+
+type FriendListHandler struct {
+	FriendListRepo repository.FriendListRepository
+}
+
+func NewFriendListHandler(FriendListRepo repository.FriendListRepository) *FriendListHandler {
+	return &FriendListHandler{FriendListRepo: FriendListRepo}
+}
+
+// RegisterRoutes registers the routes for friend list operations. Friends in a list are retrieved with /get_friend_list?id=1&list_id=2.
+func (h *FriendListHandler) RegisterRoutes(e *echo.Echo) {
+	// bonus path ex: /friend_list?id=1&name=Family response: 200 {"id":2,"owner_id":1,"name":"Family","member_count":0} or 400 "Invalid name" or 404 "Not found" or 409 "Already exists" or 500 "Failed to create friend list"
+	e.POST("/friend_list", h.CreateFriendList)
+
+	// bonus path ex: /friend_lists?id=1 response: 200 [{"id":2,"owner_id":1,"name":"Family","member_count":3}] or 500 "Failed to get friend lists"
+	e.GET("/friend_lists", h.GetFriendLists)
+
+	// bonus path ex: /friend_list?id=1&list_id=2&name=Relatives response: 200 "Friend list renamed" or 400 "Invalid name" or 404 "Not found" or 409 "Already exists" or 500 "Failed to rename friend list"
+	e.PATCH("/friend_list", h.RenameFriendList)
+
+	// bonus path ex: /friend_list?id=1&list_id=2 response: 200 "Friend list deleted" or 404 "Not found" or 500 "Failed to delete friend list"
+	e.DELETE("/friend_list", h.DeleteFriendList)
+
+	// bonus path ex: /friend_list_member?id=1&list_id=2&friend_id=3 response: 200 "Friend added to list" or 404 "Not found" or 409 "Already exists" or 422 "Not friends" or 500 "Failed to add friend to list"
+	e.POST("/friend_list_member", h.AddFriendListMember)
+
+	// bonus path ex: /friend_list_member?id=1&list_id=2&friend_id=3 response: 200 "Friend removed from list" or 404 "Not found" or 500 "Failed to remove friend from list"
+	e.DELETE("/friend_list_member", h.RemoveFriendListMember)
+}
+
+// CreateFriendList handles POST requests to create a friend list
+func (h *FriendListHandler) CreateFriendList(c echo.Context) error {
+	userIDParam := c.QueryParam("id")
+	userID, err := strconv.ParseInt(userIDParam, 10, 64)
+	if err != nil {
+		logutils.Error("Invalid user id")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid user id")
+	}
+
+	name, ok := textutils.FreeText(c.QueryParam("name"), maxFriendListNameLength, false)
+	if !ok || name == "" {
+		logutils.Error("Invalid name")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid name")
+	}
+
+	list, err := h.FriendListRepo.CreateFriendList(userID, name)
+	if err != nil {
+		return repositoryError(err, "Failed to create friend list")
+	}
+
+	return dataResponse(c, list)
+}
+
+// GetFriendLists handles GET requests to retrieve a user's friend lists
+func (h *FriendListHandler) GetFriendLists(c echo.Context) error {
+	userIDParam := c.QueryParam("id")
+	userID, err := strconv.ParseInt(userIDParam, 10, 64)
+	if err != nil {
+		logutils.Error("Invalid user id")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid user id")
+	}
+
+	lists, err := h.FriendListRepo.GetFriendLists(userID)
+	if err != nil {
+		return repositoryError(err, "Failed to get friend lists")
+	}
+	if lists == nil {
+		lists = []models.FriendList{}
+	}
+
+	return collectionResponse(c, lists, len(lists))
+}
+
+// RenameFriendList handles PATCH requests to rename a friend list
+func (h *FriendListHandler) RenameFriendList(c echo.Context) error {
+	userIDParam := c.QueryParam("id")
+	userID, err := strconv.ParseInt(userIDParam, 10, 64)
+	if err != nil {
+		logutils.Error("Invalid user id")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid user id")
+	}
+
+	listIDParam := c.QueryParam("list_id")
+	listID, err := strconv.ParseInt(listIDParam, 10, 64)
+	if err != nil {
+		logutils.Error("Invalid list id")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid list id")
+	}
+
+	name, ok := textutils.FreeText(c.QueryParam("name"), maxFriendListNameLength, false)
+	if !ok || name == "" {
+		logutils.Error("Invalid name")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid name")
+	}
+
+	err = h.FriendListRepo.RenameFriendList(userID, listID, name)
+	if err != nil {
+		return repositoryError(err, "Failed to rename friend list")
+	}
+
+	return messageResponse(c, "Friend list renamed")
+}
+
+// DeleteFriendList handles DELETE requests to delete a friend list
+func (h *FriendListHandler) DeleteFriendList(c echo.Context) error {
+	userIDParam := c.QueryParam("id")
+	userID, err := strconv.ParseInt(userIDParam, 10, 64)
+	if err != nil {
+		logutils.Error("Invalid user id")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid user id")
+	}
+
+	listIDParam := c.QueryParam("list_id")
+	listID, err := strconv.ParseInt(listIDParam, 10, 64)
+	if err != nil {
+		logutils.Error("Invalid list id")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid list id")
+	}
+
+	err = h.FriendListRepo.DeleteFriendList(userID, listID)
+	if err != nil {
+		return repositoryError(err, "Failed to delete friend list")
+	}
+
+	return messageResponse(c, "Friend list deleted")
+}
+
+// AddFriendListMember handles POST requests to add a friend to a friend list
+func (h *FriendListHandler) AddFriendListMember(c echo.Context) error {
+	userID, listID, friendID, err := friendListMemberParams(c)
+	if err != nil {
+		return err
+	}
+
+	err = h.FriendListRepo.AddFriendListMember(userID, listID, friendID)
+	if err != nil {
+		return repositoryError(err, "Failed to add friend to list")
+	}
+
+	return messageResponse(c, "Friend added to list")
+}
+
+// RemoveFriendListMember handles DELETE requests to remove a friend from a friend list
+func (h *FriendListHandler) RemoveFriendListMember(c echo.Context) error {
+	userID, listID, friendID, err := friendListMemberParams(c)
+	if err != nil {
+		return err
+	}
+
+	err = h.FriendListRepo.RemoveFriendListMember(userID, listID, friendID)
+	if err != nil {
+		return repositoryError(err, "Failed to remove friend from list")
+	}
+
+	return messageResponse(c, "Friend removed from list")
+}
+
+// friendListMemberParams reads the id, list_id and friend_id query parameters of the membership routes.
+func friendListMemberParams(c echo.Context) (int64, int64, int64, error) {
+	userIDParam := c.QueryParam("id")
+	userID, err := strconv.ParseInt(userIDParam, 10, 64)
+	if err != nil {
+		logutils.Error("Invalid user id")
+		return 0, 0, 0, newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid user id")
+	}
+
+	listIDParam := c.QueryParam("list_id")
+	listID, err := strconv.ParseInt(listIDParam, 10, 64)
+	if err != nil {
+		logutils.Error("Invalid list id")
+		return 0, 0, 0, newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid list id")
+	}
+
+	friendIDParam := c.QueryParam("friend_id")
+	friendID, err := strconv.ParseInt(friendIDParam, 10, 64)
+	if err != nil {
+		logutils.Error("Invalid friend id")
+		return 0, 0, 0, newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid friend id")
+	}
+
+	return userID, listID, friendID, nil
+}
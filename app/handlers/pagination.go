@@ -0,0 +1,99 @@
+// handlers/pagination.go
+package handlers
+
+import (
+	"encoding/base64"
+	"errors"
+	"minimal_sns_app/domain/models"
+	"minimal_sns_app/logutils"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// maxPageLimit is the most items a list route returns at once; a larger limit is lowered to it.
+const maxPageLimit = 100
+
+// maxPageNumber is the last page a page based list route serves, which keeps (page-1)*limit far from overflowing.
+const maxPageNumber = 100000
+
+// pageLimit reads the limit query parameter, lowered to maxPageLimit.
+func pageLimit(c echo.Context) (int, error) {
+	limit, err := strconv.Atoi(c.QueryParam("limit"))
+	if err != nil || limit <= 0 {
+		logutils.Error("Invalid limit")
+		return 0, newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid limit")
+	}
+	return min(limit, maxPageLimit), nil
+}
+
+// pageNumber reads the page query parameter, from 1 to maxPageNumber.
+func pageNumber(c echo.Context) (int, error) {
+	page, err := strconv.Atoi(c.QueryParam("page"))
+	if err != nil || page <= 0 || page > maxPageNumber {
+		logutils.Error("Invalid page number")
+		return 0, newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid page number")
+	}
+	return page, nil
+}
+
+// cursorPrefix versions the cursor format so that it can change without breaking clients silently.
+const cursorPrefix = "v1:"
+
+// encodeCursor turns the last ID of a page into an opaque cursor.
+func encodeCursor(lastID int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(cursorPrefix + strconv.FormatInt(lastID, 10)))
+}
+
+// decodeCursor returns the ID a cursor points after. An empty cursor starts from the beginning.
+func decodeCursor(cursor string) (int64, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, err
+	}
+	idPart, ok := strings.CutPrefix(string(decoded), cursorPrefix)
+	if !ok {
+		return 0, errors.New("unknown cursor format")
+	}
+	return strconv.ParseInt(idPart, 10, 64)
+}
+
+// listByCursor serves a cursor based list route: it reads id, cursor and limit from the query,
+// fetches one extra row to find out whether another page exists, and writes the page with its next cursor.
+func listByCursor(c echo.Context, fetch func(userID int64, afterID int64, limit int) ([]models.Friend, error), failMessage string) error {
+	userIDParam := c.QueryParam("id")
+	userID, err := strconv.ParseInt(userIDParam, 10, 64)
+	if err != nil {
+		logutils.Error("Invalid user id")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid user id")
+	}
+
+	limit, err := pageLimit(c)
+	if err != nil {
+		return err
+	}
+
+	afterID, err := decodeCursor(c.QueryParam("cursor"))
+	if err != nil {
+		logutils.Error("Invalid cursor")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid cursor")
+	}
+
+	friends, err := fetch(userID, afterID, limit+1)
+	if err != nil {
+		return repositoryError(err, failMessage)
+	}
+
+	meta := Meta{Limit: limit}
+	if len(friends) > limit {
+		friends = friends[:limit]
+		meta.NextCursor = encodeCursor(friends[limit-1].ID)
+	}
+
+	return pageResponse(c, friends, meta)
+}
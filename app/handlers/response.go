@@ -0,0 +1,131 @@
+// handlers/response.go
+package handlers
+
+import (
+	"errors"
+	"minimal_sns_app/domain/models"
+	"minimal_sns_app/logutils"
+	"minimal_sns_app/repository"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Error codes returned in the error field of the response envelope.
+const (
+	codeInvalidParameter = "invalid_parameter"
+	codeNotFound         = "not_found"
+	codeAlreadyFriends   = "already_friends"
+	codeDuplicate        = "duplicate"
+	codeNotPending       = "not_pending"
+	codeSelfReference    = "self_reference"
+	codeBlocked          = "blocked"
+	codeCooldown         = "cooldown"
+	codeNotFriends       = "not_friends"
+	codeInternalError    = "internal_error"
+)
+
+// Response is the envelope every route responds with. The response examples next to each route show its Data
+// on success, with Meta for lists, and its Error message on failure; Data is null when Error is set.
+type Response struct {
+	Data  interface{}  `json:"data"`
+	Meta  *Meta        `json:"meta,omitempty"`
+	Error *ErrorDetail `json:"error,omitempty"`
+}
+
+// Meta describes the list returned in Data.
+// Total is omitted for cursor based lists, and Page for everything but page based lists.
+type Meta struct {
+	Total      *int   `json:"total,omitempty"`
+	Limit      int    `json:"limit,omitempty"`
+	Page       int    `json:"page,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// ErrorDetail describes why a request failed. Code is meant for machines, Message for humans.
+// RequestID identifies the request in the server logs.
+type ErrorDetail struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// Message is the data returned by routes that only report an outcome.
+type Message struct {
+	Message string `json:"message"`
+}
+
+// dataResponse writes a single resource in the envelope.
+func dataResponse(c echo.Context, data interface{}) error {
+	return c.JSON(http.StatusOK, Response{Data: data})
+}
+
+// messageResponse writes the outcome of a mutation in the envelope.
+func messageResponse(c echo.Context, message string) error {
+	return c.JSON(http.StatusOK, Response{Data: Message{Message: message}})
+}
+
+// listResponse writes a complete list in the envelope, with its length as the total.
+func listResponse(c echo.Context, friends []models.Friend) error {
+	total := len(friends)
+	return pageResponse(c, friends, Meta{Total: &total})
+}
+
+// collectionResponse writes a complete list of anything but friends in the envelope, with total as its length.
+// items must not be a nil slice, which would be written as null.
+func collectionResponse(c echo.Context, items interface{}, total int) error {
+	return c.JSON(http.StatusOK, Response{Data: items, Meta: &Meta{Total: &total}})
+}
+
+// collectionPageResponse writes a part of a list of anything but friends in the envelope.
+// items must not be a nil slice, which would be written as null.
+func collectionPageResponse(c echo.Context, items interface{}, meta Meta) error {
+	return c.JSON(http.StatusOK, Response{Data: items, Meta: &meta})
+}
+
+// pageResponse writes a part of a list in the envelope. A nil list is written as an empty array.
+func pageResponse(c echo.Context, friends []models.Friend, meta Meta) error {
+	if friends == nil {
+		friends = []models.Friend{}
+	}
+	return c.JSON(http.StatusOK, Response{Data: friends, Meta: &meta})
+}
+
+// newHTTPError creates an error that HTTPErrorHandler writes in the envelope.
+// message is written in English and translated for clients preferring another language.
+func newHTTPError(status int, code string, message string) *echo.HTTPError {
+	return echo.NewHTTPError(status, &ErrorDetail{Code: code, Message: message})
+}
+
+// repositoryError converts the error returned by a repository into an HTTP error.
+// Known repository errors get their own status and code, anything else is a 500 with failMessage.
+func repositoryError(err error, failMessage string) error {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		logutils.Error("Not found")
+		return newHTTPError(http.StatusNotFound, codeNotFound, "Not found")
+	case errors.Is(err, repository.ErrAlreadyFriends):
+		logutils.Error("Already friends")
+		return newHTTPError(http.StatusConflict, codeAlreadyFriends, "Already friends")
+	case errors.Is(err, repository.ErrDuplicate):
+		logutils.Error("Already exists")
+		return newHTTPError(http.StatusConflict, codeDuplicate, "Already exists")
+	case errors.Is(err, repository.ErrNotPending):
+		logutils.Error("Friend request is not pending")
+		return newHTTPError(http.StatusConflict, codeNotPending, "Friend request is not pending")
+	case errors.Is(err, repository.ErrSelfReference):
+		logutils.Error("Cannot target yourself")
+		return newHTTPError(http.StatusUnprocessableEntity, codeSelfReference, "Cannot target yourself")
+	case errors.Is(err, repository.ErrBlocked):
+		logutils.Error("Blocked user")
+		return newHTTPError(http.StatusForbidden, codeBlocked, "Blocked user")
+	case errors.Is(err, repository.ErrNotFriends):
+		logutils.Error("Not friends")
+		return newHTTPError(http.StatusUnprocessableEntity, codeNotFriends, "Not friends")
+	case errors.Is(err, repository.ErrCooldown):
+		logutils.Error("Friend request was declined recently")
+		return newHTTPError(http.StatusTooManyRequests, codeCooldown, "Friend request was declined recently")
+	}
+	logutils.Error(failMessage)
+	return newHTTPError(http.StatusInternalServerError, codeInternalError, failMessage)
+}
@@ -2,8 +2,15 @@
 package handlers
 
 import (
+	"errors"
+	"minimal_sns_app/configs"
+	"minimal_sns_app/domain/models"
 	"minimal_sns_app/logutils"
 	"minimal_sns_app/repository"
+	"minimal_sns_app/textutils"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"net/http"
 	"strconv"
@@ -11,77 +18,369 @@ import (
 	"github.com/labstack/echo/v4"
 )
 
+// Number of characters each profile field can hold, as in the users table.
+const (
+	maxDisplayNameLength = 64
+	maxBioLength         = 500
+	maxLocationLength    = 100
+	maxAvatarPathLength  = 255
+)
+
+// maxSearchQueryLength is the number of characters a user search query can hold, as many as a name.
+const maxSearchQueryLength = 64
+
+// maxBatchUserIDs is the number of IDs /users accepts at once.
+const maxBatchUserIDs = 100
+
+// minBirthday is the earliest birthday a profile accepts.
+var minBirthday = time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC)
+
 type UserHandler struct {
 	UserRepo repository.UserRepository
+	// DeactivationGracePeriod is how long a deactivated user can be restored.
+	DeactivationGracePeriod time.Duration
 }
 
-func NewUserHandler(UserRepo repository.UserRepository) *UserHandler {
-	return &UserHandler{UserRepo: UserRepo}
+func NewUserHandler(UserRepo repository.UserRepository, conf configs.UserConfig) *UserHandler {
+	return &UserHandler{UserRepo: UserRepo, DeactivationGracePeriod: conf.DeactivationGracePeriod}
 }
 
-// RegisterRoutes registers user routes.
+// RegisterRoutes registers the routes for user operations
 func (h *UserHandler) RegisterRoutes(e *echo.Echo) {
-	// bonus path ex: /user?id=1 response: 200 {"id":1,"name":"alice"} or 404 "not found"
+	// bonus path ex: /user?id=1 or /user?name=alice response: 200 {"id":1,"name":"alice"} or 400 "Specify either id or name" or 404 "Not found"
 	e.GET("/user", h.GetUser)
 
-	// bonus path ex: /user?name=alice response: 200 {"id":1,"name":"alice"} or 500 "internal server error"
+	// bonus path ex: /users?ids=1,2,3 response: 200 {"users":[{"id":1,"name":"alice"},{"id":3,"name":"charlie"}],"missing_ids":[2]} or 400 "Invalid user ids" or 500 "Failed to get users"
+	// Users come in the order of ids, without duplicates, and at most 100 ids are accepted
+	e.GET("/users", h.GetUsers)
+
+	// bonus path ex: /user?name=alice response: 200 {"id":1,"name":"alice"} or 400 "Invalid name" or 400 "Reserved name" or 409 "Already exists" or 500 "Internal server error"
+	// The name is normalized to NFKC and must be 3 to 30 letters, digits or underscores. It is unique regardless of case
 	e.POST("/user", h.CreateUser)
 
-	// bonus path ex: /user?id=1 response : 200 "success" or 404 "not found"
+	// bonus path ex: /check_name_available?name=alice response: 200 {"name":"alice","available":false,"reason":"taken"} or 500 "Failed to check name"
+	// reason is invalid, reserved or taken when the name is not available
+	e.GET("/check_name_available", h.CheckNameAvailable)
+
+	// bonus path ex: /user?id=1 body: {"display_name":"Alice","bio":"hello","birthday":"2000-01-31","location":"Tokyo","avatar_path":"avatars/1.png"} (every field optional) response: 200 {"id":1,"name":"alice","display_name":"Alice",...} or 400 "Invalid display name" or 400 "Invalid bio" or 400 "Invalid birthday" or 400 "Invalid location" or 400 "Invalid avatar path" or 400 "Nothing to update" or 404 "Not found" or 500 "Failed to update user"
+	// The name is the user's unique handle and is not part of the profile, an empty birthday removes it
+	e.PATCH("/user", h.UpdateUser)
+
+	// bonus path ex: /search_users?id=1&q=ali&limit=10&cursor=djE6Mg response: 200 [{"id":2,"name":"alice",...}] with meta.next_cursor or 400 "Invalid query" or 500 "Failed to search users"
+	// id is the searcher, and users who blocked them are left out. q ignores width, case and kana differences (ＡＬＩ, ali; アリス, ありす).
+	// match (optional) is prefix (default) or substring, which is slower as it cannot use the index
+	e.GET("/search_users", h.SearchUsers)
+
+	// bonus path ex: /user?id=1 response : 200 "success" or 404 "Not found" or 500 "Failed to deactivate user"
+	// The user is deactivated: hidden everywhere, restorable during the grace period and deleted for good after it
 	e.DELETE("/user", h.DeleteUser)
+
+	// bonus path ex: /restore_user?id=1 response: 200 {"id":1,"name":"alice",...} or 404 "Not found" when the user is not deactivated or the grace period is over or 500 "Failed to restore user"
+	e.POST("/restore_user", h.RestoreUser)
 }
 
-// GetUser retrieves a user by ID.
+// GetUser retrieves a user by ID or by name.
 func (h *UserHandler) GetUser(c echo.Context) error {
 	userIDPram := c.QueryParam("id")
-	userID, err := strconv.ParseInt(userIDPram, 10, 64)
+	name := c.QueryParam("name")
+	if (userIDPram == "") == (name == "") {
+		logutils.Error("Specify either id or name")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Specify either id or name")
+	}
+
+	var user *models.User
+	if name != "" {
+		var err error
+		user, err = h.UserRepo.GetUserByName(name)
+		if err != nil {
+			logutils.Error(err.Error())
+			return newHTTPError(http.StatusInternalServerError, codeInternalError, "Internal server error")
+		}
+	} else {
+		userID, err := strconv.ParseInt(userIDPram, 10, 64)
+		if err != nil {
+			logutils.Error(err.Error())
+			return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid user id")
+		}
+
+		user, err = h.UserRepo.GetUser(userID)
+		if err != nil {
+			logutils.Error(err.Error())
+			return newHTTPError(http.StatusInternalServerError, codeInternalError, "Internal server error")
+		}
+	}
+	if user == nil {
+		return newHTTPError(http.StatusNotFound, codeNotFound, "Not found")
+	}
+
+	return dataResponse(c, user)
+}
+
+// GetUsers retrieves several users by ID in one request, reporting the IDs no user was found for.
+func (h *UserHandler) GetUsers(c echo.Context) error {
+	userIDs, err := batchUserIDs(c.QueryParam("ids"))
 	if err != nil {
-		logutils.Error(err.Error())
-		return echo.NewHTTPError(http.StatusBadRequest, "invalid user id")
+		logutils.Error("Invalid user ids")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid user ids")
 	}
 
-	user, err := h.UserRepo.GetUser(userID)
+	found, err := h.UserRepo.GetUsers(userIDs)
 	if err != nil {
-		logutils.Error(err.Error())
-		return echo.NewHTTPError(http.StatusInternalServerError, "internal server error")
+		return repositoryError(err, "Failed to get users")
 	}
-	if user == nil {
-		return echo.NewHTTPError(http.StatusNotFound, "not found")
+
+	// Put the users back in the order they were asked for
+	byID := make(map[int64]models.User, len(found))
+	for _, user := range found {
+		byID[user.ID] = user
+	}
+	batch := models.UserBatch{Users: []models.User{}, MissingIDs: []int64{}}
+	for _, userID := range userIDs {
+		if user, ok := byID[userID]; ok {
+			batch.Users = append(batch.Users, user)
+		} else {
+			batch.MissingIDs = append(batch.MissingIDs, userID)
+		}
 	}
 
-	return c.JSON(http.StatusOK, user)
+	return dataResponse(c, batch)
+}
+
+// batchUserIDs parses the comma separated ids of /users, dropping duplicates but keeping the order.
+// It fails when an ID is not a number or when there are none or more than maxBatchUserIDs.
+func batchUserIDs(param string) ([]int64, error) {
+	var userIDs []int64
+	seen := make(map[int64]bool)
+	for _, part := range strings.Split(param, ",") {
+		userID, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[userID] {
+			seen[userID] = true
+			userIDs = append(userIDs, userID)
+		}
+	}
+	if len(userIDs) > maxBatchUserIDs {
+		return nil, errors.New("too many user ids")
+	}
+	return userIDs, nil
 }
 
-// CreateUser creates a new user.
+// CreateUser creates a new user whose name follows the handle policy.
 func (h *UserHandler) CreateUser(c echo.Context) error {
-	name := c.QueryParam("name")
-	if name == "" {
-		return echo.NewHTTPError(http.StatusBadRequest, "invalid name")
+	name := textutils.NormalizeHandle(c.QueryParam("name"))
+	if err := textutils.CheckHandle(name); err != nil {
+		if errors.Is(err, textutils.ErrReservedHandle) {
+			logutils.Error("Reserved name")
+			return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Reserved name")
+		}
+		logutils.Error("Invalid name")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid name")
 	}
 
 	user, err := h.UserRepo.CreateUser(name)
+	if err != nil {
+		return repositoryError(err, "Internal server error")
+	}
+
+	return dataResponse(c, user)
+}
+
+// CheckNameAvailable tells whether a name can be used to create a user, and why not when it cannot.
+func (h *UserHandler) CheckNameAvailable(c echo.Context) error {
+	name := textutils.NormalizeHandle(c.QueryParam("name"))
+	availability := models.NameAvailability{Name: name}
+
+	switch err := textutils.CheckHandle(name); {
+	case errors.Is(err, textutils.ErrReservedHandle):
+		availability.Reason = models.NameReserved
+	case err != nil:
+		availability.Reason = models.NameInvalid
+	default:
+		taken, err := h.UserRepo.IsNameTaken(name)
+		if err != nil {
+			return repositoryError(err, "Failed to check name")
+		}
+		if taken {
+			availability.Reason = models.NameTaken
+		}
+	}
+	availability.Available = availability.Reason == ""
+
+	return dataResponse(c, availability)
+}
+
+// UpdateUser handles PATCH requests to change the profile fields given in the JSON body, leaving the others as they are.
+func (h *UserHandler) UpdateUser(c echo.Context) error {
+	userIDPram := c.QueryParam("id")
+	userID, err := strconv.ParseInt(userIDPram, 10, 64)
 	if err != nil {
 		logutils.Error(err.Error())
-		return echo.NewHTTPError(http.StatusInternalServerError, "internal server error")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid user id")
+	}
+
+	var update models.UserUpdate
+	if err := c.Bind(&update); err != nil {
+		logutils.Error("Invalid request body")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid request body")
+	}
+	if err := validateUserUpdate(&update); err != nil {
+		return err
 	}
 
-	return c.JSON(http.StatusOK, user)
+	user, err := h.UserRepo.UpdateUser(userID, update)
+	if err != nil {
+		return repositoryError(err, "Failed to update user")
+	}
+
+	return dataResponse(c, user)
+}
+
+// validateUserUpdate trims the fields set in update and checks them one by one,
+// returning a 400 naming the first invalid field.
+func validateUserUpdate(update *models.UserUpdate) error {
+	if update.DisplayName == nil && update.Bio == nil && update.Birthday == nil && update.Location == nil && update.AvatarPath == nil {
+		logutils.Error("Nothing to update")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Nothing to update")
+	}
+
+	fields := []struct {
+		value     *string
+		valid     func(string) bool
+		message   string
+		maxLength int
+		multiline bool
+	}{
+		{update.DisplayName, nil, "Invalid display name", maxDisplayNameLength, false},
+		{update.Bio, nil, "Invalid bio", maxBioLength, true},
+		{update.Birthday, validBirthday, "Invalid birthday", len("2006-01-02"), false},
+		{update.Location, nil, "Invalid location", maxLocationLength, false},
+		{update.AvatarPath, validAvatarPath, "Invalid avatar path", maxAvatarPathLength, false},
+	}
+	for _, field := range fields {
+		if field.value == nil {
+			continue
+		}
+		value, ok := textutils.FreeText(*field.value, field.maxLength, field.multiline)
+		if ok && value != "" && field.valid != nil {
+			ok = field.valid(value)
+		}
+		if !ok {
+			logutils.Error(field.message)
+			return newHTTPError(http.StatusBadRequest, codeInvalidParameter, field.message)
+		}
+		*field.value = value
+	}
+	return nil
+}
+
+// validBirthday reports whether birthday is a YYYY-MM-DD date between minBirthday and today.
+func validBirthday(birthday string) bool {
+	date, err := time.Parse("2006-01-02", birthday)
+	if err != nil {
+		return false
+	}
+	return !date.Before(minBirthday) && !date.After(time.Now().UTC())
+}
+
+// validAvatarPath reports whether path is a relative path to an uploaded image: slash separated segments of
+// ASCII letters, digits, dots, hyphens and underscores, none of them pointing to a parent directory.
+func validAvatarPath(path string) bool {
+	for _, segment := range strings.Split(path, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return false
+		}
+		for _, r := range segment {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' || r == '_') {
+				return false
+			}
+		}
+	}
+	return true
 }
 
-// DeleteUser deletes a user by ID.
+// SearchUsers handles GET requests to find users by name, a page at a time.
+func (h *UserHandler) SearchUsers(c echo.Context) error {
+	userIDPram := c.QueryParam("id")
+	userID, err := strconv.ParseInt(userIDPram, 10, 64)
+	if err != nil {
+		logutils.Error(err.Error())
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid user id")
+	}
+
+	query := strings.TrimSpace(c.QueryParam("q"))
+	if query == "" || !utf8.ValidString(query) || utf8.RuneCountInString(query) > maxSearchQueryLength {
+		logutils.Error("Invalid query")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid query")
+	}
+
+	match := c.QueryParam("match")
+	switch match {
+	case "":
+		match = models.UserSearchPrefix
+	case models.UserSearchPrefix, models.UserSearchSubstring:
+	default:
+		logutils.Error("Invalid match")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid match")
+	}
+
+	limit, err := pageLimit(c)
+	if err != nil {
+		return err
+	}
+
+	afterID, err := decodeCursor(c.QueryParam("cursor"))
+	if err != nil {
+		logutils.Error("Invalid cursor")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid cursor")
+	}
+
+	// One extra row tells whether another page exists
+	users, err := h.UserRepo.SearchUsers(userID, query, match, afterID, limit+1)
+	if err != nil {
+		return repositoryError(err, "Failed to search users")
+	}
+
+	meta := Meta{Limit: limit}
+	if len(users) > limit {
+		users = users[:limit]
+		meta.NextCursor = encodeCursor(users[limit-1].ID)
+	}
+
+	return collectionPageResponse(c, users, meta)
+}
+
+// DeleteUser deactivates a user by ID. The user is deleted for good once the grace period is over.
 func (h *UserHandler) DeleteUser(c echo.Context) error {
 	userIDPram := c.QueryParam("id")
 	userID, err := strconv.ParseInt(userIDPram, 10, 64)
 	if err != nil {
 		logutils.Error(err.Error())
-		return echo.NewHTTPError(http.StatusBadRequest, "invalid user id")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid user id")
 	}
 
-	err = h.UserRepo.DeleteUser(userID)
+	err = h.UserRepo.DeactivateUser(userID)
+	if err != nil {
+		return repositoryError(err, "Failed to deactivate user")
+	}
+
+	return messageResponse(c, "success")
+}
+
+// RestoreUser restores a user deactivated within the grace period.
+func (h *UserHandler) RestoreUser(c echo.Context) error {
+	userIDPram := c.QueryParam("id")
+	userID, err := strconv.ParseInt(userIDPram, 10, 64)
 	if err != nil {
 		logutils.Error(err.Error())
-		return echo.NewHTTPError(http.StatusInternalServerError, "internal server error")
+		return newHTTPError(http.StatusBadRequest, codeInvalidParameter, "Invalid user id")
+	}
+
+	user, err := h.UserRepo.RestoreUser(userID, h.DeactivationGracePeriod)
+	if err != nil {
+		return repositoryError(err, "Failed to restore user")
 	}
 
-	return c.JSON(http.StatusOK, "success")
+	return dataResponse(c, user)
 }
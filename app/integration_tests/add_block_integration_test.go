@@ -0,0 +1,129 @@
+package integration_tests
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"minimal_sns_app/configs"
+	"minimal_sns_app/handlers"
+	"minimal_sns_app/logutils"
+	"minimal_sns_app/repository"
+	"minimal_sns_app/testhelpers"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/labstack/echo/v4"
+)
+
+// テスト対象 /add_block?id=1&block_id=2 response: 200 "User blocked" or 500 "Failed to add to block list"
+func TestAddBlockIntegration(t *testing.T) {
+	logutils.InitLog()
+	// 初期設定
+	e := echo.New()
+	conf := configs.Get()
+	db, err := sql.Open(conf.DB.Driver, conf.DB.DataSource)
+	if err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	// リポジトリとハンドラーの設定
+	friendRepo := repository.NewFriendRepository(db)
+	friendHandler := handlers.NewFriendHandler(friendRepo)
+	friendHandler.RegisterRoutes(e)
+
+	// テストサーバーの設定
+	ts := httptest.NewServer(e)
+	defer ts.Close()
+
+	aliceID, bobID, cleanupFunc, err := setupTestDataForAddBlock(db)
+	if err != nil {
+		t.Fatalf("failed to setup test data: %v", err)
+	}
+	defer cleanupFunc()
+
+	client := &http.Client{}
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s/add_block?id=%d&block_id=%d", ts.URL, aliceID, bobID), nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("failed to execute request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	testhelpers.AssertEqual(t, http.StatusOK, resp.StatusCode)
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read response body: %v", err)
+	}
+	testhelpers.AssertEqual(t, "User blocked", testhelpers.DecodeMessage(t, bodyBytes))
+
+	// 双方向の友達関係と申請中のリクエストが削除されていることを確認
+	var linkCount, requestCount int
+	err = db.QueryRow("SELECT COUNT(*) FROM friend_link WHERE user1_id IN (?, ?)", aliceID, bobID).Scan(&linkCount)
+	if err != nil {
+		t.Fatalf("failed to query for friend link count: %v", err)
+	}
+	testhelpers.AssertEqual(t, 0, linkCount)
+	err = db.QueryRow("SELECT COUNT(*) FROM friend_requests WHERE status = 'pending'").Scan(&requestCount)
+	if err != nil {
+		t.Fatalf("failed to query for friend request count: %v", err)
+	}
+	testhelpers.AssertEqual(t, 0, requestCount)
+
+	// ブロックされた側からのリクエストは拒否される
+	req2, err := http.NewRequest("POST", fmt.Sprintf("%s/request_friend?id=%d&friend_id=%d", ts.URL, bobID, aliceID), nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+
+	resp2, err := client.Do(req2)
+	if err != nil {
+		t.Fatalf("failed to execute request: %v", err)
+	}
+	defer resp2.Body.Close()
+
+	testhelpers.AssertEqual(t, http.StatusForbidden, resp2.StatusCode)
+}
+
+func setupTestDataForAddBlock(db *sql.DB) (int64, int64, func(), error) {
+	// UserRepositoryを使ってテストデータを作成する
+	userRepo := repository.NewUserRepository(db)
+
+	alice, err := userRepo.CreateUser("alice") // テストデータの作成
+	if err != nil {
+		return 0, 0, nil, fmt.Errorf("failed to create user1: %v", err)
+	}
+	bob, err := userRepo.CreateUser("bob") // テストデータの作成
+	if err != nil {
+		return 0, 0, nil, fmt.Errorf("failed to create user2: %v", err)
+	}
+
+	// alice と bob は友達で、bob から alice への申請も残っている
+	query := "INSERT INTO friend_link (user1_id, user2_id) VALUES (?, ?), (?, ?)"
+	if _, err := db.Exec(query, alice.ID, bob.ID, bob.ID, alice.ID); err != nil {
+		return 0, 0, nil, fmt.Errorf("failed to create friend: %v", err)
+	}
+	friendRepo := repository.NewFriendRepository(db)
+	if _, err := friendRepo.RequestFriend(bob.ID, alice.ID, ""); err != nil {
+		return 0, 0, nil, fmt.Errorf("failed to create friend request: %v", err)
+	}
+
+	// テストデータの削除用関数
+	cleanupFunc := func() {
+		// テーブルのデータを全削除する
+		for _, table := range []string{"friend_requests", "block_list", "friend_link", "users"} {
+			if _, err := db.Exec("DELETE FROM " + table); err != nil {
+				panic(err)
+			}
+		}
+	}
+
+	return alice.ID, bob.ID, cleanupFunc, nil
+}
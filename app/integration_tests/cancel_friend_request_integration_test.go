@@ -0,0 +1,125 @@
+package integration_tests
+
+import (
+	"fmt"
+	"io"
+	"minimal_sns_app/configs"
+	"minimal_sns_app/domain/models"
+	"minimal_sns_app/handlers"
+	"minimal_sns_app/repository"
+	"minimal_sns_app/testhelpers"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"database/sql"
+
+	"github.com/labstack/echo/v4"
+)
+
+// テスト対象 /cancel_friend_request?id=1&friend_id=2 response: 200 "Friend request canceled" or 404 "Not found" or 409 "Friend request is not pending" or 500 "Failed to cancel friend request"
+func TestCancelFriendRequestIntegration(t *testing.T) {
+	// 初期設定
+	e := echo.New()
+	conf := configs.Get()
+	db, err := sql.Open(conf.DB.Driver, conf.DB.DataSource)
+	if err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	// リポジトリとハンドラーの設定
+	friendRepo := repository.NewFriendRepository(db)
+	friendHandler := handlers.NewFriendHandler(friendRepo)
+	friendHandler.RegisterRoutes(e)
+
+	// テストサーバーの設定
+	ts := httptest.NewServer(e)
+	defer ts.Close()
+
+	aliceID, bobID, cleanupFunc, err := setupTestDataForCancelFriendRequest(db)
+	if err != nil {
+		t.Fatalf("failed to setup test data: %v", err)
+	}
+	defer cleanupFunc()
+
+	client := &http.Client{}
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s/cancel_friend_request?id=%d&friend_id=%d", ts.URL, aliceID, bobID), nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("failed to execute request: %v", err)
+	}
+
+	testhelpers.AssertEqual(t, http.StatusOK, resp.StatusCode)
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read response body: %v", err)
+	}
+	testhelpers.AssertEqual(t, "Friend request canceled", testhelpers.DecodeMessage(t, bodyBytes))
+
+	// 取り消した申請は申請先一覧からすぐに消える
+	resp2, err := client.Get(fmt.Sprintf("%s/get_friend_requested_list?id=%d", ts.URL, aliceID))
+	if err != nil {
+		t.Fatalf("failed to execute request: %v", err)
+	}
+	defer resp2.Body.Close()
+
+	bodyBytes2, err := io.ReadAll(resp2.Body)
+	if err != nil {
+		t.Fatalf("failed to read response body: %v", err)
+	}
+	var requested []models.Friend
+	testhelpers.DecodeResponse(t, bodyBytes2, &requested)
+	testhelpers.AssertEqual(t, 0, len(requested))
+
+	// 取り消した申請は履歴に残るため、二重の取り消しは 409 になる
+	resp3, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("failed to execute request: %v", err)
+	}
+	defer resp3.Body.Close()
+	testhelpers.AssertEqual(t, http.StatusConflict, resp3.StatusCode)
+}
+
+func setupTestDataForCancelFriendRequest(db *sql.DB) (int64, int64, func(), error) {
+	// UserRepositoryを使ってテストデータを作成する
+	userRepo := repository.NewUserRepository(db)
+
+	alice, err := userRepo.CreateUser("alice") // テストデータの作成
+	if err != nil {
+		return 0, 0, nil, fmt.Errorf("failed to create user1: %v", err)
+	}
+	bob, err := userRepo.CreateUser("bob") // テストデータの作成
+	if err != nil {
+		return 0, 0, nil, fmt.Errorf("failed to create user2: %v", err)
+	}
+
+	// FriendRepositoryを使ってテストデータを作成する
+	friendRepo := repository.NewFriendRepository(db)
+	_, err = friendRepo.RequestFriend(alice.ID, bob.ID, "")
+	if err != nil {
+		return 0, 0, nil, fmt.Errorf("failed to create friend request: %v", err)
+	}
+
+	// テストデータの削除用関数
+	cleanupFunc := func() {
+		// テーブルのデータを全削除する
+		query := "DELETE FROM users"
+		_, err := db.Exec(query)
+		if err != nil {
+			panic(err)
+		}
+		query = "DELETE FROM friend_requests"
+		_, err = db.Exec(query)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	return alice.ID, bob.ID, cleanupFunc, nil
+}
@@ -0,0 +1,152 @@
+package integration_tests
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"minimal_sns_app/configs"
+	"minimal_sns_app/domain/models"
+	"minimal_sns_app/handlers"
+	"minimal_sns_app/repository"
+	"minimal_sns_app/testhelpers"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"database/sql"
+
+	"github.com/labstack/echo/v4"
+)
+
+// テスト対象 /admin/check_friendships と /admin/repair_friendships
+// 不整合を種類ごとに検出し、修復後は不整合がなくなること
+func TestCheckFriendshipsIntegration(t *testing.T) {
+	// 初期設定
+	e := echo.New()
+	conf := configs.Get()
+	db, err := sql.Open(conf.DB.Driver, conf.DB.DataSource)
+	if err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	// リポジトリとハンドラーの設定
+	invariantRepo := repository.NewInvariantRepository(db)
+	adminHandler := handlers.NewAdminHandler(invariantRepo)
+	adminHandler.RegisterRoutes(e)
+
+	// テストサーバーの設定
+	ts := httptest.NewServer(e)
+	defer ts.Close()
+
+	cleanupFunc, err := setupTestDataForCheckFriendships(db)
+	if err != nil {
+		t.Fatalf("failed to setup test data: %v", err)
+	}
+	defer cleanupFunc()
+
+	// 検査では種類ごとの件数が返る
+	resp, err := http.Get(fmt.Sprintf("%s/admin/check_friendships?limit=10", ts.URL))
+	if err != nil {
+		t.Fatalf("failed to execute request: %v", err)
+	}
+	defer resp.Body.Close()
+	testhelpers.AssertEqual(t, http.StatusOK, resp.StatusCode)
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read response body: %v", err)
+	}
+	var report models.FriendshipReport
+	testhelpers.DecodeResponse(t, bodyBytes, &report)
+
+	expectedCounts := map[string]int{
+		models.InconsistencyBlockedLink:           2,
+		models.InconsistencyOneSidedLink:          1,
+		models.InconsistencyAcceptedWithoutLink:   1,
+		models.InconsistencyPendingBetweenFriends: 1,
+		models.InconsistencyBlockedFollow:         2,
+		models.InconsistencyListMemberNotFriend:   1,
+	}
+	testhelpers.AssertDeepEqual(t, expectedCounts, report.Counts)
+	testhelpers.AssertEqual(t, 8, len(report.Inconsistencies))
+
+	// 0 件ずつの修復は終わらないため、リポジトリが拒否する
+	if _, err := invariantRepo.RepairFriendships(0); !errors.Is(err, repository.ErrInvalidBatchSize) {
+		t.Errorf("expected ErrInvalidBatchSize, got %v", err)
+	}
+
+	// 修復後は不整合がなくなる
+	resp2, err := http.Post(fmt.Sprintf("%s/admin/repair_friendships?batch_size=1", ts.URL), "", nil)
+	if err != nil {
+		t.Fatalf("failed to execute request: %v", err)
+	}
+	defer resp2.Body.Close()
+	testhelpers.AssertEqual(t, http.StatusOK, resp2.StatusCode)
+
+	bodyBytes2, err := io.ReadAll(resp2.Body)
+	if err != nil {
+		t.Fatalf("failed to read response body: %v", err)
+	}
+	var repairedReport models.FriendshipReport
+	testhelpers.DecodeResponse(t, bodyBytes2, &repairedReport)
+
+	testhelpers.AssertEqual(t, 0, len(repairedReport.Inconsistencies))
+	for kind, count := range repairedReport.Counts {
+		testhelpers.AssertEqual(t, 0, count)
+		// ブロック中の友達関係は1行の修復で両方向が消え、フォローも消えるため、修復件数は検出件数と一致しない
+		if kind != models.InconsistencyBlockedLink && kind != models.InconsistencyBlockedFollow {
+			testhelpers.AssertEqual(t, expectedCounts[kind], repairedReport.Repaired[kind])
+		}
+	}
+	// 友達でないユーザー間のフォローだけがフォローとして修復される
+	testhelpers.AssertEqual(t, 1, repairedReport.Repaired[models.InconsistencyBlockedFollow])
+}
+
+func setupTestDataForCheckFriendships(db *sql.DB) (func(), error) {
+	// UserRepositoryを使ってテストデータを作成する
+	userRepo := repository.NewUserRepository(db)
+	users := make(map[string]int64)
+	for _, name := range []string{"alice", "bob", "charlie", "david", "eve", "frank", "grace"} {
+		user, err := userRepo.CreateUser(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create user %s: %v", name, err)
+		}
+		users[name] = user.ID
+	}
+
+	statements := []struct {
+		query string
+		args  []interface{}
+	}{
+		// 片方向だけの友達関係
+		{"INSERT INTO friend_link (user1_id, user2_id) VALUES (?, ?)", []interface{}{users["alice"], users["bob"]}},
+		// ブロックし合っているのに友達
+		{"INSERT INTO friend_link (user1_id, user2_id) VALUES (?, ?), (?, ?)", []interface{}{users["charlie"], users["david"], users["david"], users["charlie"]}},
+		{"INSERT INTO block_list (user1_id, user2_id) VALUES (?, ?)", []interface{}{users["charlie"], users["david"]}},
+		// ブロックし合っているのにフォローしている（david は友達でもある）
+		{"INSERT INTO follow (follower_id, followee_id) VALUES (?, ?), (?, ?)", []interface{}{users["david"], users["charlie"], users["grace"], users["frank"]}},
+		{"INSERT INTO block_list (user1_id, user2_id) VALUES (?, ?)", []interface{}{users["frank"], users["grace"]}},
+		// 友達でないのにリストに入っている
+		{"INSERT INTO friend_lists (owner_id, name) VALUES (?, 'family')", []interface{}{users["bob"]}},
+		{"INSERT INTO friend_list_members (list_id, member_id) SELECT id, ? FROM friend_lists WHERE owner_id = ?", []interface{}{users["grace"], users["bob"]}},
+		// 承認済みなのに友達でない
+		{"INSERT INTO friend_requests (requester_id, requested_id, status) VALUES (?, ?, 'accepted')", []interface{}{users["eve"], users["frank"]}},
+		// 友達なのに申請中
+		{"INSERT INTO friend_link (user1_id, user2_id) VALUES (?, ?), (?, ?)", []interface{}{users["alice"], users["eve"], users["eve"], users["alice"]}},
+		{"INSERT INTO friend_requests (requester_id, requested_id) VALUES (?, ?)", []interface{}{users["alice"], users["eve"]}},
+	}
+	for _, statement := range statements {
+		if _, err := db.Exec(statement.query, statement.args...); err != nil {
+			return nil, fmt.Errorf("failed to insert test data: %v", err)
+		}
+	}
+
+	// テストデータの削除用関数（友達関係、申請、ブロックは users の削除で連鎖して消える）
+	cleanupFunc := func() {
+		if _, err := db.Exec("DELETE FROM users"); err != nil {
+			panic(err)
+		}
+	}
+	return cleanupFunc, nil
+}
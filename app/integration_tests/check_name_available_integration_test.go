@@ -0,0 +1,136 @@
+package integration_tests
+
+import (
+	"fmt"
+	"io"
+	"minimal_sns_app/configs"
+	"minimal_sns_app/domain/models"
+	"minimal_sns_app/handlers"
+	"minimal_sns_app/repository"
+	"minimal_sns_app/testhelpers"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"database/sql"
+
+	"github.com/labstack/echo/v4"
+)
+
+// テスト対象 /check_name_available?name=alice response: 200 {"name":"alice","available":false,"reason":"taken"} or 500 "Failed to check name"
+// あわせて /user の作成で名前の規則（文字種、長さ、NFKC 正規化、予約語、大文字小文字を区別しない重複）が守られ、ひらがなとカタカナ、アクセントの有無は別の名前として扱われることを確認する
+func TestCheckNameAvailableIntegration(t *testing.T) {
+	// 初期設定
+	e := echo.New()
+	e.HTTPErrorHandler = handlers.HTTPErrorHandler
+	conf := configs.Get()
+	db, err := sql.Open(conf.DB.Driver, conf.DB.DataSource)
+	if err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	// リポジトリとハンドラーの設定
+	userRepo := repository.NewUserRepository(db)
+	userHandler := handlers.NewUserHandler(userRepo, conf.User)
+	userHandler.RegisterRoutes(e)
+
+	// テストサーバーの設定
+	ts := httptest.NewServer(e)
+	defer ts.Close()
+
+	defer func() {
+		// テーブルのデータを全削除する
+		if _, err := db.Exec("DELETE FROM users"); err != nil {
+			panic(err)
+		}
+	}()
+
+	client := &http.Client{}
+	create := func(name string) (int, []byte) {
+		resp, err := client.Post(fmt.Sprintf("%s/user?name=%s", ts.URL, url.QueryEscape(name)), "", nil)
+		if err != nil {
+			t.Fatalf("failed to execute request: %v", err)
+		}
+		defer resp.Body.Close()
+		bodyBytes, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("failed to read response body: %v", err)
+		}
+		return resp.StatusCode, bodyBytes
+	}
+
+	// 全角の名前は NFKC で半角にそろえて登録される
+	status, bodyBytes := create("Ａｌｉｃｅ＿１")
+	testhelpers.AssertEqual(t, http.StatusOK, status)
+	var user models.User
+	testhelpers.DecodeResponse(t, bodyBytes, &user)
+	testhelpers.AssertEqual(t, "Alice_1", user.Name)
+
+	tests := []struct {
+		name     string
+		handle   string
+		expected int
+	}{
+		{"大文字小文字だけが違う名前", "alice_1", http.StatusConflict},
+		{"短すぎる名前", "ab", http.StatusBadRequest},
+		{"長すぎる名前", strings.Repeat("a", 31), http.StatusBadRequest},
+		{"空白を含む名前", "a b c", http.StatusBadRequest},
+		{"記号を含む名前", "a-b-c", http.StatusBadRequest},
+		{"予約語", "Admin", http.StatusBadRequest},
+		{"日本語の名前", "ありす", http.StatusOK},
+		{"ひらがなの名前と読みが同じカタカナの名前", "アリス", http.StatusOK},
+		{"アクセントのない名前", "cafe", http.StatusOK},
+		{"アクセントだけが違う名前", "café", http.StatusOK},
+		{"大文字小文字だけが違うアクセント付きの名前", "CAFÉ", http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, _ := create(tt.handle)
+			testhelpers.AssertEqual(t, tt.expected, status)
+		})
+	}
+
+	// 名前が使えるかどうかと、使えない理由が返る
+	checks := []struct {
+		handle    string
+		available bool
+		reason    string
+	}{
+		{"ALICE_1", false, models.NameTaken},
+		{"ＡＬＩＣＥ＿１", false, models.NameTaken},
+		{"root", false, models.NameReserved},
+		{"a b", false, models.NameInvalid},
+		{"bob", true, ""},
+		{"アリス", false, models.NameTaken},
+		{"ありさ", true, ""},
+		{"cafè", true, ""},
+	}
+	for _, check := range checks {
+		resp, err := client.Get(fmt.Sprintf("%s/check_name_available?name=%s", ts.URL, url.QueryEscape(check.handle)))
+		if err != nil {
+			t.Fatalf("failed to execute request: %v", err)
+		}
+		bodyBytes, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("failed to read response body: %v", err)
+		}
+		testhelpers.AssertEqual(t, http.StatusOK, resp.StatusCode)
+
+		var availability models.NameAvailability
+		testhelpers.DecodeResponse(t, bodyBytes, &availability)
+		testhelpers.AssertEqual(t, check.available, availability.Available)
+		testhelpers.AssertEqual(t, check.reason, availability.Reason)
+	}
+
+	// 名前での取得も大文字小文字と全角半角を区別しない
+	resp, err := client.Get(fmt.Sprintf("%s/user?name=%s", ts.URL, url.QueryEscape("ＡＬＩＣＥ_1")))
+	if err != nil {
+		t.Fatalf("failed to execute request: %v", err)
+	}
+	resp.Body.Close()
+	testhelpers.AssertEqual(t, http.StatusOK, resp.StatusCode)
+}
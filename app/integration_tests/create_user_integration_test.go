@@ -2,7 +2,6 @@ package integration_tests
 
 import (
 	"database/sql"
-	"encoding/json"
 	"fmt"
 	"io"
 	"minimal_sns_app/configs"
@@ -31,7 +30,7 @@ func TestCreateUserIntegration(t *testing.T) {
 
 	e := echo.New()
 	userRepo := repository.NewUserRepository(db)
-	userHandler := handlers.NewUserHandler(userRepo)
+	userHandler := handlers.NewUserHandler(userRepo, conf.User)
 	userHandler.RegisterRoutes(e)
 
 	ts := httptest.NewServer(e)
@@ -58,10 +57,7 @@ func TestCreateUserIntegration(t *testing.T) {
 	}
 
 	var user models.User
-	err = json.Unmarshal(bodyBytes, &user)
-	if err != nil {
-		t.Fatalf("failed to unmarshal response body: %v", err)
-	}
+	testhelpers.DecodeResponse(t, bodyBytes, &user)
 
 	testhelpers.AssertEqual(t, userName, user.Name)
 }
@@ -0,0 +1,161 @@
+package integration_tests
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"minimal_sns_app/configs"
+	"minimal_sns_app/domain/models"
+	"minimal_sns_app/handlers"
+	"minimal_sns_app/repository"
+	"minimal_sns_app/testhelpers"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"database/sql"
+
+	"github.com/labstack/echo/v4"
+)
+
+// テスト対象 /restore_user?id=1 response: 200 {"id":1,"name":"alice",...} or 404 "Not found" or 500 "Failed to restore user"
+// 退会したユーザーが友達一覧や検索から隠れ、猶予期間内なら復元でき、期間後は完全に削除されることを確認する
+func TestDeactivateUserIntegration(t *testing.T) {
+	// 初期設定
+	e := echo.New()
+	e.HTTPErrorHandler = handlers.HTTPErrorHandler
+	conf := configs.Get()
+	db, err := sql.Open(conf.DB.Driver, conf.DB.DataSource)
+	if err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	// リポジトリとハンドラーの設定
+	userRepo := repository.NewUserRepository(db)
+	userHandler := handlers.NewUserHandler(userRepo, conf.User)
+	userHandler.RegisterRoutes(e)
+	friendRepo := repository.NewFriendRepository(db)
+	friendHandler := handlers.NewFriendHandler(friendRepo)
+	friendHandler.RegisterRoutes(e)
+	followRepo := repository.NewFollowRepository(db)
+	followHandler := handlers.NewFollowHandler(followRepo)
+	followHandler.RegisterRoutes(e)
+
+	// テストサーバーの設定
+	ts := httptest.NewServer(e)
+	defer ts.Close()
+
+	var users []*models.User
+	for _, name := range []string{"alice", "bob", "charlie"} {
+		user, err := userRepo.CreateUser(name)
+		if err != nil {
+			t.Fatalf("failed to create user %s: %v", name, err)
+		}
+		users = append(users, user)
+	}
+	alice, bob, charlie := users[0], users[1], users[2]
+	defer func() {
+		// テーブルのデータを全削除する
+		if _, err := db.Exec("DELETE FROM users"); err != nil {
+			panic(err)
+		}
+	}()
+
+	// alice と bob、bob と charlie が友達
+	query := `INSERT INTO friend_link (user1_id, user2_id) VALUES (?, ?), (?, ?), (?, ?), (?, ?)`
+	if _, err := db.Exec(query, alice.ID, bob.ID, bob.ID, alice.ID, bob.ID, charlie.ID, charlie.ID, bob.ID); err != nil {
+		t.Fatalf("failed to insert friend links: %v", err)
+	}
+
+	client := &http.Client{}
+	do := func(method string, path string) (int, []byte) {
+		req, err := http.NewRequest(method, ts.URL+path, nil)
+		if err != nil {
+			t.Fatalf("failed to create request: %v", err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("failed to execute request: %v", err)
+		}
+		defer resp.Body.Close()
+		bodyBytes, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("failed to read response body: %v", err)
+		}
+		return resp.StatusCode, bodyBytes
+	}
+	count := func(path string) int {
+		status, bodyBytes := do(http.MethodGet, path)
+		testhelpers.AssertEqual(t, http.StatusOK, status)
+		var friends []models.Friend
+		testhelpers.DecodeResponse(t, bodyBytes, &friends)
+		return len(friends)
+	}
+
+	// bob が退会する
+	status, _ := do(http.MethodDelete, fmt.Sprintf("/user?id=%d", bob.ID))
+	testhelpers.AssertEqual(t, http.StatusOK, status)
+
+	// bob は友達一覧、検索から消え、bob を経由した友達の友達もいなくなる
+	testhelpers.AssertEqual(t, 0, count(fmt.Sprintf("/get_friend_list?id=%d", alice.ID)))
+	testhelpers.AssertEqual(t, 0, count(fmt.Sprintf("/get_friend_of_friend_list?id=%d", alice.ID)))
+	testhelpers.AssertEqual(t, 0, count(fmt.Sprintf("/search_users?id=%d&q=bob&limit=10", alice.ID)))
+
+	// 退会したユーザーへの友達申請も、退会したユーザーからの友達申請やフォローも 404 になる
+	status, _ = do(http.MethodPost, fmt.Sprintf("/request_friend?id=%d&friend_id=%d", charlie.ID, bob.ID))
+	testhelpers.AssertEqual(t, http.StatusNotFound, status)
+	status, _ = do(http.MethodPost, fmt.Sprintf("/request_friend?id=%d&friend_id=%d", bob.ID, charlie.ID))
+	testhelpers.AssertEqual(t, http.StatusNotFound, status)
+	status, _ = do(http.MethodPost, fmt.Sprintf("/follow?id=%d&target_id=%d", bob.ID, charlie.ID))
+	testhelpers.AssertEqual(t, http.StatusNotFound, status)
+
+	// 退会したユーザーとの共通の友達や申請履歴も 404 になる
+	status, _ = do(http.MethodGet, fmt.Sprintf("/get_mutual_friend_list?id=%d&other_id=%d", charlie.ID, bob.ID))
+	testhelpers.AssertEqual(t, http.StatusNotFound, status)
+	status, _ = do(http.MethodGet, fmt.Sprintf("/get_friend_request_history?id=%d&friend_id=%d", charlie.ID, bob.ID))
+	testhelpers.AssertEqual(t, http.StatusNotFound, status)
+
+	// 猶予期間内なら復元でき、友達関係も元に戻る
+	status, bodyBytes := do(http.MethodPost, fmt.Sprintf("/restore_user?id=%d", bob.ID))
+	testhelpers.AssertEqual(t, http.StatusOK, status)
+	var restored models.User
+	testhelpers.DecodeResponse(t, bodyBytes, &restored)
+	testhelpers.AssertEqual(t, bob.ID, restored.ID)
+	testhelpers.AssertEqual(t, 1, count(fmt.Sprintf("/get_friend_list?id=%d", alice.ID)))
+	testhelpers.AssertEqual(t, 1, count(fmt.Sprintf("/get_friend_of_friend_list?id=%d", alice.ID)))
+
+	// 退会していないユーザーは復元できない
+	status, _ = do(http.MethodPost, fmt.Sprintf("/restore_user?id=%d", bob.ID))
+	testhelpers.AssertEqual(t, http.StatusNotFound, status)
+
+	// 猶予期間を過ぎた退会は復元できない
+	status, _ = do(http.MethodDelete, fmt.Sprintf("/user?id=%d", bob.ID))
+	testhelpers.AssertEqual(t, http.StatusOK, status)
+	gracePeriod := 24 * time.Hour
+	if _, err := db.Exec("UPDATE users SET deactivated_at = NOW() - INTERVAL 2 DAY WHERE id = ?", bob.ID); err != nil {
+		t.Fatalf("failed to update user: %v", err)
+	}
+	if _, err := userRepo.RestoreUser(bob.ID, gracePeriod); err == nil {
+		t.Errorf("expected the restore to fail after the grace period")
+	}
+
+	// 0 件ずつの削除は終わらないため拒否される
+	if _, err := userRepo.PurgeDeactivatedUsers(gracePeriod, 0); !errors.Is(err, repository.ErrInvalidBatchSize) {
+		t.Errorf("expected ErrInvalidBatchSize, got %v", err)
+	}
+
+	// 削除ジョブが行を完全に削除し、友達関係も連鎖して消える
+	purged, err := userRepo.PurgeDeactivatedUsers(gracePeriod, 1)
+	if err != nil {
+		t.Fatalf("failed to purge users: %v", err)
+	}
+	testhelpers.AssertEqual(t, 1, purged)
+
+	var links int
+	if err := db.QueryRow("SELECT COUNT(*) FROM friend_link WHERE user1_id = ? OR user2_id = ?", bob.ID, bob.ID).Scan(&links); err != nil {
+		t.Fatalf("failed to count friend links: %v", err)
+	}
+	testhelpers.AssertEqual(t, 0, links)
+}
@@ -59,7 +59,7 @@ func TestDeclineFriendIntegration(t *testing.T) {
 	if err != nil {
 		t.Fatalf("failed to read response body: %v", err)
 	}
-	testhelpers.AssertEqual(t, "Friend request declined", string(bodyBytes))
+	testhelpers.AssertEqual(t, "Friend request declined", testhelpers.DecodeMessage(t, bodyBytes))
 }
 
 func setupTestDataForDeclineFriend(db *sql.DB) (int64, int64, func(), error) {
@@ -77,7 +77,7 @@ func setupTestDataForDeclineFriend(db *sql.DB) (int64, int64, func(), error) {
 
 	// FriendRepositoryを使ってテストデータを作成する
 	friendRepo := repository.NewFriendRepository(db)
-	err = friendRepo.RequestFriend(alice.ID, bob.ID)
+	_, err = friendRepo.RequestFriend(alice.ID, bob.ID, "")
 	if err != nil {
 		return 0, 0, nil, fmt.Errorf("failed to create friend request: %v", err)
 	}
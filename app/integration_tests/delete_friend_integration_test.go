@@ -76,7 +76,26 @@ func TestDeleteFrinedIntegration(t *testing.T) {
 	if err != nil {
 		t.Fatalf("failed to read response body: %v", err)
 	}
-	testhelpers.AssertEqual(t, "success", string(bodyBytes))
+	testhelpers.AssertEqual(t, "success", testhelpers.DecodeMessage(t, bodyBytes))
+
+	// 両方向の友達関係が消え、承認済みの申請は ended になる
+	var linkCount int
+	err = db.QueryRow("SELECT COUNT(*) FROM friend_link WHERE user1_id IN (?, ?)", targetID1, targetID2).Scan(&linkCount)
+	testhelpers.AssertNoError(t, err)
+	testhelpers.AssertEqual(t, 0, linkCount)
+
+	var status string
+	err = db.QueryRow("SELECT status FROM friend_requests WHERE requester_id = ? AND requested_id = ?", targetID2, targetID1).Scan(&status)
+	testhelpers.AssertNoError(t, err)
+	testhelpers.AssertEqual(t, "ended", status)
+
+	// 友達でない相手の削除は 404 になる
+	resp2, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("failed to execute request: %v", err)
+	}
+	defer resp2.Body.Close()
+	testhelpers.AssertEqual(t, http.StatusNotFound, resp2.StatusCode)
 }
 
 func setupTestDataForDeleteFriend(db *sql.DB) (targetID1 int64, targetID2 int64, cleanupFunc func(), err error) {
@@ -101,9 +120,14 @@ func setupTestDataForDeleteFriend(db *sql.DB) (targetID1 int64, targetID2 int64,
 		return 0, 0, nil, fmt.Errorf("failed to get test data: %v", err)
 	}
 
-	// 友達関係をセットアップ
-	query = `INSERT INTO friend_link (user1_id, user2_id) VALUES (?, ?)`
-	_, err = db.Exec(query, targetID1, targetID2)
+	// 友達関係をセットアップ（test2 からの申請を test1 が承認した状態）
+	query = `INSERT INTO friend_link (user1_id, user2_id) VALUES (?, ?), (?, ?)`
+	_, err = db.Exec(query, targetID1, targetID2, targetID2, targetID1)
+	if err != nil {
+		return 0, 0, nil, fmt.Errorf("failed to insert test data: %v", err)
+	}
+	query = `INSERT INTO friend_requests (requester_id, requested_id, status) VALUES (?, ?, 'accepted')`
+	_, err = db.Exec(query, targetID2, targetID1)
 	if err != nil {
 		return 0, 0, nil, fmt.Errorf("failed to insert test data: %v", err)
 	}
@@ -34,7 +34,7 @@ func TestDeleteUserIntegration(t *testing.T) {
 
 	e := echo.New()
 	userRepo := repository.NewUserRepository(db)
-	userHandler := handlers.NewUserHandler(userRepo)
+	userHandler := handlers.NewUserHandler(userRepo, conf.User)
 	userHandler.RegisterRoutes(e)
 
 	ts := httptest.NewServer(e)
@@ -54,14 +54,33 @@ func TestDeleteUserIntegration(t *testing.T) {
 
 	testhelpers.AssertEqual(t, http.StatusOK, resp.StatusCode)
 
-	// Confirm that the user no longer exists in the database
-	var userCount int
-	err = db.QueryRow("SELECT COUNT(*) FROM users WHERE id = ?", createdUserID).Scan(&userCount)
+	// 行は猶予期間のあいだ残り、退会日時が入っている
+	var deactivated bool
+	err = db.QueryRow("SELECT deactivated_at IS NOT NULL FROM users WHERE id = ?", createdUserID).Scan(&deactivated)
 	if err != nil {
-		t.Fatalf("failed to query for user count: %v", err)
+		t.Fatalf("failed to query for user: %v", err)
 	}
-	if userCount > 0 {
-		t.Errorf("user with ID %d was not deleted", createdUserID)
+	testhelpers.AssertEqual(t, true, deactivated)
+
+	// 退会したユーザーは取得できず、もう一度の削除や存在しないユーザーの削除は 404 になる
+	resp, err = client.Get(fmt.Sprintf("%s/user?id=%d", ts.URL, createdUserID))
+	if err != nil {
+		t.Fatalf("failed to execute request: %v", err)
+	}
+	resp.Body.Close()
+	testhelpers.AssertEqual(t, http.StatusNotFound, resp.StatusCode)
+
+	for _, userID := range []int64{createdUserID, createdUserID + 1000} {
+		req, err := http.NewRequest("DELETE", fmt.Sprintf("%s/user?id=%d", ts.URL, userID), nil)
+		if err != nil {
+			t.Fatalf("failed to create request: %v", err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("failed to execute request: %v", err)
+		}
+		resp.Body.Close()
+		testhelpers.AssertEqual(t, http.StatusNotFound, resp.StatusCode)
 	}
 }
 
@@ -0,0 +1,98 @@
+package integration_tests
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"minimal_sns_app/configs"
+	"minimal_sns_app/domain/models"
+	"minimal_sns_app/handlers"
+	"minimal_sns_app/repository"
+	"minimal_sns_app/testhelpers"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"database/sql"
+
+	"github.com/labstack/echo/v4"
+)
+
+// テスト対象 ExpireFriendRequests 期限切れの申請が expired になり、申請者一覧から消えること
+func TestExpireFriendRequestsIntegration(t *testing.T) {
+	// 初期設定
+	e := echo.New()
+	conf := configs.Get()
+	db, err := sql.Open(conf.DB.Driver, conf.DB.DataSource)
+	if err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	// リポジトリとハンドラーの設定
+	friendRepo := repository.NewFriendRepository(db)
+	friendHandler := handlers.NewFriendHandler(friendRepo)
+	friendHandler.RegisterRoutes(e)
+
+	// テストサーバーの設定
+	ts := httptest.NewServer(e)
+	defer ts.Close()
+
+	userRepo := repository.NewUserRepository(db)
+	users := make(map[string]int64)
+	for _, name := range []string{"alice", "bob", "charlie", "david"} {
+		user, err := userRepo.CreateUser(name)
+		if err != nil {
+			t.Fatalf("failed to create user %s: %v", name, err)
+		}
+		users[name] = user.ID
+	}
+	defer func() {
+		// テーブルのデータを全削除する（申請と履歴は users の削除で連鎖して消える）
+		if _, err := db.Exec("DELETE FROM users"); err != nil {
+			panic(err)
+		}
+	}()
+
+	// alice, bob, charlie が david に申請し、alice と bob の申請だけを古くする
+	for _, name := range []string{"alice", "bob", "charlie"} {
+		if _, err := friendRepo.RequestFriend(users[name], users["david"], ""); err != nil {
+			t.Fatalf("failed to create friend request: %v", err)
+		}
+	}
+	if _, err := db.Exec("UPDATE friend_requests SET created_at = NOW() - INTERVAL 2 DAY WHERE requester_id IN (?, ?)", users["alice"], users["bob"]); err != nil {
+		t.Fatalf("failed to update friend requests: %v", err)
+	}
+
+	// 0 件ずつの処理は終わらないため拒否され、何も期限切れにならない
+	if _, err := friendRepo.ExpireFriendRequests(24*time.Hour, 0); !errors.Is(err, repository.ErrInvalidBatchSize) {
+		t.Errorf("expected ErrInvalidBatchSize, got %v", err)
+	}
+
+	// バッチサイズ1でも全件が期限切れになる
+	expired, err := friendRepo.ExpireFriendRequests(24*time.Hour, 1)
+	testhelpers.AssertNoError(t, err)
+	testhelpers.AssertEqual(t, 2, expired)
+
+	resp, err := http.Get(fmt.Sprintf("%s/get_friend_requester_list?id=%d", ts.URL, users["david"]))
+	if err != nil {
+		t.Fatalf("failed to execute request: %v", err)
+	}
+	defer resp.Body.Close()
+	testhelpers.AssertEqual(t, http.StatusOK, resp.StatusCode)
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read response body: %v", err)
+	}
+	var requesters []models.Friend
+	testhelpers.DecodeResponse(t, bodyBytes, &requesters)
+	testhelpers.AssertEqual(t, 1, len(requesters))
+	if len(requesters) == 1 {
+		testhelpers.AssertEqual(t, users["charlie"], requesters[0].ID)
+	}
+
+	// 期限切れの申請は承認できない
+	testhelpers.AssertEqual(t, repository.ErrNotPending, friendRepo.AcceptFriend(users["david"], users["alice"]))
+}
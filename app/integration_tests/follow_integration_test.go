@@ -0,0 +1,106 @@
+package integration_tests
+
+import (
+	"fmt"
+	"io"
+	"minimal_sns_app/configs"
+	"minimal_sns_app/domain/models"
+	"minimal_sns_app/handlers"
+	"minimal_sns_app/repository"
+	"minimal_sns_app/testhelpers"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"database/sql"
+
+	"github.com/labstack/echo/v4"
+)
+
+// テスト対象 /follow, /get_follower_list, /get_follow_count とブロックとの連携
+func TestFollowIntegration(t *testing.T) {
+	// 初期設定
+	e := echo.New()
+	e.HTTPErrorHandler = handlers.HTTPErrorHandler
+	conf := configs.Get()
+	db, err := sql.Open(conf.DB.Driver, conf.DB.DataSource)
+	if err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	// リポジトリとハンドラーの設定
+	friendRepo := repository.NewFriendRepository(db)
+	followRepo := repository.NewFollowRepository(db)
+	handlers.NewFollowHandler(followRepo).RegisterRoutes(e)
+
+	// テストサーバーの設定
+	ts := httptest.NewServer(e)
+	defer ts.Close()
+
+	userRepo := repository.NewUserRepository(db)
+	users := make(map[string]int64)
+	for _, name := range []string{"alice", "bob", "charlie"} {
+		user, err := userRepo.CreateUser(name)
+		if err != nil {
+			t.Fatalf("failed to create user %s: %v", name, err)
+		}
+		users[name] = user.ID
+	}
+	defer func() {
+		// テーブルのデータを全削除する（フォローとブロックは users の削除で連鎖して消える）
+		if _, err := db.Exec("DELETE FROM users"); err != nil {
+			panic(err)
+		}
+	}()
+
+	client := &http.Client{}
+	follow := func(userID int64, targetID int64) int {
+		resp, err := client.Post(fmt.Sprintf("%s/follow?id=%d&target_id=%d", ts.URL, userID, targetID), "", nil)
+		if err != nil {
+			t.Fatalf("failed to execute request: %v", err)
+		}
+		defer resp.Body.Close()
+		return resp.StatusCode
+	}
+	get := func(path string, data interface{}) {
+		resp, err := client.Get(ts.URL + path)
+		if err != nil {
+			t.Fatalf("failed to execute request: %v", err)
+		}
+		defer resp.Body.Close()
+		testhelpers.AssertEqual(t, http.StatusOK, resp.StatusCode)
+		bodyBytes, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("failed to read response body: %v", err)
+		}
+		testhelpers.DecodeResponse(t, bodyBytes, data)
+	}
+
+	// alice と charlie が bob をフォローする（承認は不要）
+	testhelpers.AssertEqual(t, http.StatusOK, follow(users["alice"], users["bob"]))
+	testhelpers.AssertEqual(t, http.StatusOK, follow(users["charlie"], users["bob"]))
+	// 二重のフォローと自分自身のフォローはエラーになる
+	testhelpers.AssertEqual(t, http.StatusConflict, follow(users["alice"], users["bob"]))
+	testhelpers.AssertEqual(t, http.StatusUnprocessableEntity, follow(users["alice"], users["alice"]))
+
+	var followers []models.Friend
+	get(fmt.Sprintf("/get_follower_list?id=%d&limit=10", users["bob"]), &followers)
+	testhelpers.AssertEqual(t, 2, len(followers))
+
+	var counts models.FollowCounts
+	get(fmt.Sprintf("/get_follow_count?id=%d", users["bob"]), &counts)
+	testhelpers.AssertEqual(t, models.FollowCounts{Followers: 2, Following: 0}, counts)
+
+	// bob が alice をブロックするとフォローが消え、再フォローもできない
+	if err := friendRepo.AddBlock(users["bob"], users["alice"]); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+	get(fmt.Sprintf("/get_follow_count?id=%d", users["bob"]), &counts)
+	testhelpers.AssertEqual(t, models.FollowCounts{Followers: 1, Following: 0}, counts)
+	testhelpers.AssertEqual(t, http.StatusForbidden, follow(users["alice"], users["bob"]))
+
+	var following []models.Friend
+	get(fmt.Sprintf("/get_following_list?id=%d&limit=10", users["alice"]), &following)
+	testhelpers.AssertEqual(t, 0, len(following))
+}
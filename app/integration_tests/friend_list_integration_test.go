@@ -0,0 +1,156 @@
+package integration_tests
+
+import (
+	"fmt"
+	"io"
+	"minimal_sns_app/configs"
+	"minimal_sns_app/domain/models"
+	"minimal_sns_app/handlers"
+	"minimal_sns_app/repository"
+	"minimal_sns_app/testhelpers"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"database/sql"
+
+	"github.com/labstack/echo/v4"
+)
+
+// テスト対象 /friend_list, /friend_list_member, /get_friend_list?list_id=
+// 友達以外はリストに追加できず、友達解除とブロックでリストから外れること
+func TestFriendListIntegration(t *testing.T) {
+	// 初期設定
+	e := echo.New()
+	e.HTTPErrorHandler = handlers.HTTPErrorHandler
+	conf := configs.Get()
+	db, err := sql.Open(conf.DB.Driver, conf.DB.DataSource)
+	if err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	// リポジトリとハンドラーの設定
+	friendRepo := repository.NewFriendRepository(db)
+	handlers.NewFriendHandler(friendRepo).RegisterRoutes(e)
+	handlers.NewFriendListHandler(repository.NewFriendListRepository(db)).RegisterRoutes(e)
+
+	// テストサーバーの設定
+	ts := httptest.NewServer(e)
+	defer ts.Close()
+
+	users, cleanupFunc, err := setupTestDataForFriendList(db)
+	if err != nil {
+		t.Fatalf("failed to setup test data: %v", err)
+	}
+	defer cleanupFunc()
+
+	client := &http.Client{}
+	do := func(method string, path string, data interface{}) int {
+		req, err := http.NewRequest(method, ts.URL+path, nil)
+		if err != nil {
+			t.Fatalf("failed to create request: %v", err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("failed to execute request: %v", err)
+		}
+		defer resp.Body.Close()
+		bodyBytes, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("failed to read response body: %v", err)
+		}
+		if data != nil && resp.StatusCode == http.StatusOK {
+			testhelpers.DecodeResponse(t, bodyBytes, data)
+		}
+		return resp.StatusCode
+	}
+	members := func(listID int64) []models.Friend {
+		var friends []models.Friend
+		testhelpers.AssertEqual(t, http.StatusOK, do("GET", fmt.Sprintf("/get_friend_list?id=%d&list_id=%d", users["alice"], listID), &friends))
+		return friends
+	}
+	memberNames := func(listID int64, sort string) []string {
+		var friends []models.Friend
+		testhelpers.AssertEqual(t, http.StatusOK, do("GET", fmt.Sprintf("/get_friend_list?id=%d&list_id=%d&sort=%s", users["alice"], listID, sort), &friends))
+		names := []string{}
+		for _, friend := range friends {
+			names = append(names, friend.Name)
+		}
+		return names
+	}
+
+	// リストを作成する（同じ名前は 409）
+	var list models.FriendList
+	createPath := fmt.Sprintf("/friend_list?id=%d&name=%s", users["alice"], url.QueryEscape("家族"))
+	testhelpers.AssertEqual(t, http.StatusOK, do("POST", createPath, &list))
+	testhelpers.AssertEqual(t, "家族", list.Name)
+	testhelpers.AssertEqual(t, http.StatusConflict, do("POST", createPath, nil))
+
+	// 友達は追加でき、友達でない相手は 422 になる
+	memberPath := func(friend string) string {
+		return fmt.Sprintf("/friend_list_member?id=%d&list_id=%d&friend_id=%d", users["alice"], list.ID, users[friend])
+	}
+	testhelpers.AssertEqual(t, http.StatusOK, do("POST", memberPath("bob"), nil))
+	testhelpers.AssertEqual(t, http.StatusOK, do("POST", memberPath("charlie"), nil))
+	testhelpers.AssertEqual(t, http.StatusUnprocessableEntity, do("POST", memberPath("david"), nil))
+	testhelpers.AssertEqual(t, 2, len(members(list.ID)))
+
+	// リスト内の友達も並び順を指定でき、友達になった日時の新しい順では後から友達になった charlie が先になる
+	testhelpers.AssertDeepEqual(t, []string{"bob", "charlie"}, memberNames(list.ID, models.FriendSortID))
+	testhelpers.AssertDeepEqual(t, []string{"charlie", "bob"}, memberNames(list.ID, models.FriendSortFriendedAt))
+
+	// 他人のリストは見えない
+	testhelpers.AssertEqual(t, http.StatusNotFound, do("GET", fmt.Sprintf("/get_friend_list?id=%d&list_id=%d", users["bob"], list.ID), nil))
+
+	// 友達解除とブロックでリストから外れる
+	if err := friendRepo.DeleteFriend(users["bob"], users["alice"]); err != nil {
+		t.Fatalf("failed to delete friend: %v", err)
+	}
+	testhelpers.AssertEqual(t, 1, len(members(list.ID)))
+	if err := friendRepo.AddBlock(users["charlie"], users["alice"]); err != nil {
+		t.Fatalf("failed to add block: %v", err)
+	}
+	testhelpers.AssertEqual(t, 0, len(members(list.ID)))
+
+	var lists []models.FriendList
+	testhelpers.AssertEqual(t, http.StatusOK, do("GET", fmt.Sprintf("/friend_lists?id=%d", users["alice"]), &lists))
+	testhelpers.AssertEqual(t, 1, len(lists))
+	if len(lists) == 1 {
+		testhelpers.AssertEqual(t, 0, lists[0].MemberCount)
+	}
+
+	// 削除したリストは 404 になる
+	testhelpers.AssertEqual(t, http.StatusOK, do("DELETE", fmt.Sprintf("/friend_list?id=%d&list_id=%d", users["alice"], list.ID), nil))
+	testhelpers.AssertEqual(t, http.StatusNotFound, do("GET", fmt.Sprintf("/get_friend_list?id=%d&list_id=%d", users["alice"], list.ID), nil))
+}
+
+func setupTestDataForFriendList(db *sql.DB) (map[string]int64, func(), error) {
+	// UserRepositoryを使ってテストデータを作成する
+	userRepo := repository.NewUserRepository(db)
+	users := make(map[string]int64)
+	for _, name := range []string{"alice", "bob", "charlie", "david"} {
+		user, err := userRepo.CreateUser(name)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create user %s: %v", name, err)
+		}
+		users[name] = user.ID
+	}
+
+	// alice と bob は1日前に、alice と charlie は今日友達になった
+	for friend, daysAgo := range map[string]int{"bob": 1, "charlie": 0} {
+		query := "INSERT INTO friend_link (user1_id, user2_id, created_at) VALUES (?, ?, NOW() - INTERVAL ? DAY), (?, ?, NOW() - INTERVAL ? DAY)"
+		if _, err := db.Exec(query, users["alice"], users[friend], daysAgo, users[friend], users["alice"], daysAgo); err != nil {
+			return nil, nil, fmt.Errorf("failed to insert test data: %v", err)
+		}
+	}
+
+	// テストデータの削除用関数（リストや友達関係は users の削除で連鎖して消える）
+	cleanupFunc := func() {
+		if _, err := db.Exec("DELETE FROM users"); err != nil {
+			panic(err)
+		}
+	}
+	return users, cleanupFunc, nil
+}
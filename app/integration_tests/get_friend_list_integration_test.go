@@ -3,7 +3,6 @@ package integration_tests
 
 import (
 	"database/sql"
-	"encoding/json"
 	"fmt"
 	"io"
 	"minimal_sns_app/configs"
@@ -74,8 +73,7 @@ func TestGetFriendListIntegration(t *testing.T) {
 
 	// レスポンスボディを期待する構造体にデコード
 	var gotFriends []models.Friend
-	err = json.Unmarshal(body, &gotFriends)
-	testhelpers.AssertNoError(t, err)
+	testhelpers.DecodeResponse(t, body, &gotFriends)
 	// Responseの中身からnameだけを取り出す
 	var gotFriendsName []string
 	for _, friend := range gotFriends {
@@ -0,0 +1,112 @@
+package integration_tests
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"minimal_sns_app/configs"
+	"minimal_sns_app/domain/models"
+	"minimal_sns_app/handlers"
+	"minimal_sns_app/logutils"
+	"minimal_sns_app/repository"
+	"minimal_sns_app/testhelpers"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/labstack/echo/v4"
+)
+
+// テスト対象 /get_friend_list_paging?id=1&limit=10&page=1 response: 200 [{"id":1,"name":"alice"}] or 500 "Failed to get friends with paging"
+func TestGetFriendListPagingIntegration(t *testing.T) {
+	logutils.InitLog()
+	// 初期設定
+	e := echo.New()
+	conf := configs.Get()
+	db, err := sql.Open(conf.DB.Driver, conf.DB.DataSource)
+	if err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	// リポジトリとハンドラーの設定
+	friendRepo := repository.NewFriendRepository(db)
+	friendHandler := handlers.NewFriendHandler(friendRepo)
+	friendHandler.RegisterRoutes(e)
+
+	// テストサーバーの設定
+	ts := httptest.NewServer(e)
+	defer ts.Close()
+
+	targetID, cleanupFunc, err := setupTestDataForGetFriendListPaging(db)
+	if err != nil {
+		t.Fatalf("failed to setup test data: %v", err)
+	}
+	defer cleanupFunc()
+
+	client := &http.Client{}
+
+	// page ごとに取得する
+	var gotPages [][]string
+	for page := 1; page <= 3; page++ {
+		resp, err := client.Get(fmt.Sprintf("%s/get_friend_list_paging?id=%d&limit=2&page=%d", ts.URL, targetID, page))
+		if err != nil {
+			t.Fatalf("failed to execute request: %v", err)
+		}
+
+		testhelpers.AssertEqual(t, http.StatusOK, resp.StatusCode)
+
+		bodyBytes, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("failed to read response body: %v", err)
+		}
+
+		var friends []models.Friend
+		testhelpers.DecodeResponse(t, bodyBytes, &friends)
+
+		var names []string
+		for _, friend := range friends {
+			names = append(names, friend.Name)
+		}
+		gotPages = append(gotPages, names)
+	}
+
+	// 2ページ目が飛ばされずに user4 を返し、3ページ目は空になる
+	testhelpers.AssertDeepEqual(t, [][]string{{"user2", "user3"}, {"user4"}, nil}, gotPages)
+}
+
+func setupTestDataForGetFriendListPaging(db *sql.DB) (int64, func(), error) {
+	// UserRepositoryを使ってテストデータを作成する
+	userRepo := repository.NewUserRepository(db)
+
+	var userIDs []int64
+	for i := 1; i <= 4; i++ {
+		user, err := userRepo.CreateUser(fmt.Sprintf("user%d", i))
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to create user%d: %v", i, err)
+		}
+		userIDs = append(userIDs, user.ID)
+	}
+
+	// user1 と user2~4 を友達にする
+	query := "INSERT INTO friend_link (user1_id, user2_id) VALUES (?, ?), (?, ?)"
+	for _, friendID := range userIDs[1:] {
+		if _, err := db.Exec(query, userIDs[0], friendID, friendID, userIDs[0]); err != nil {
+			return 0, nil, fmt.Errorf("failed to create friend: %v", err)
+		}
+	}
+
+	// テストデータの削除用関数
+	cleanupFunc := func() {
+		// テーブルのデータを全削除する
+		for _, table := range []string{"friend_link", "users"} {
+			if _, err := db.Exec("DELETE FROM " + table); err != nil {
+				panic(err)
+			}
+		}
+	}
+
+	return userIDs[0], cleanupFunc, nil
+}
@@ -0,0 +1,146 @@
+package integration_tests
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"minimal_sns_app/configs"
+	"minimal_sns_app/domain/models"
+	"minimal_sns_app/handlers"
+	"minimal_sns_app/logutils"
+	"minimal_sns_app/repository"
+	"minimal_sns_app/testhelpers"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/labstack/echo/v4"
+)
+
+// テスト対象 /get_friend_network?id=1&depth=3 response: 200 [{"id":5,"name":"eve"}] or 500 "Failed to get friend network"
+func TestGetFriendNetworkIntegration(t *testing.T) {
+	logutils.InitLog()
+	// 初期設定
+	e := echo.New()
+	conf := configs.Get()
+	db, err := sql.Open(conf.DB.Driver, conf.DB.DataSource)
+	if err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	// リポジトリとハンドラーの設定
+	friendRepo := repository.NewFriendRepository(db)
+	friendHandler := handlers.NewFriendHandler(friendRepo)
+	friendHandler.RegisterRoutes(e)
+
+	// テストサーバーの設定
+	ts := httptest.NewServer(e)
+	defer ts.Close()
+
+	targetID, cleanupFunc, err := setupTestDataForGetFriendNetwork(db)
+	if err != nil {
+		t.Fatalf("failed to setup test data: %v", err)
+	}
+	defer cleanupFunc()
+
+	client := &http.Client{}
+
+	// 深さごとに期待するユーザー
+	expected := map[int][]string{
+		1: {"bob", "charlie"},
+		2: {"david"},
+		3: {"eve"},
+	}
+	for depth, expectedNames := range expected {
+		resp, err := client.Get(fmt.Sprintf("%s/get_friend_network?id=%d&depth=%d", ts.URL, targetID, depth))
+		if err != nil {
+			t.Fatalf("failed to execute request: %v", err)
+		}
+
+		testhelpers.AssertEqual(t, http.StatusOK, resp.StatusCode)
+
+		bodyBytes, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("failed to read response body: %v", err)
+		}
+
+		var friends []models.Friend
+		testhelpers.DecodeResponse(t, bodyBytes, &friends)
+
+		var gotNames []string
+		for _, friend := range friends {
+			gotNames = append(gotNames, friend.Name)
+		}
+		testhelpers.AssertDeepEqual(t, expectedNames, gotNames)
+	}
+
+	// david が alice をブロックすると、david だけでなく david を経由した eve にも届かなくなる
+	var davidID int64
+	if err := db.QueryRow("SELECT id FROM users WHERE name = 'david'").Scan(&davidID); err != nil {
+		t.Fatalf("failed to get user: %v", err)
+	}
+	if _, err := db.Exec("INSERT INTO block_list (user1_id, user2_id) VALUES (?, ?)", davidID, targetID); err != nil {
+		t.Fatalf("failed to insert block: %v", err)
+	}
+	for _, depth := range []int{2, 3} {
+		resp, err := client.Get(fmt.Sprintf("%s/get_friend_network?id=%d&depth=%d", ts.URL, targetID, depth))
+		if err != nil {
+			t.Fatalf("failed to execute request: %v", err)
+		}
+		bodyBytes, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("failed to read response body: %v", err)
+		}
+		testhelpers.AssertEqual(t, http.StatusOK, resp.StatusCode)
+
+		var friends []models.Friend
+		testhelpers.DecodeResponse(t, bodyBytes, &friends)
+		testhelpers.AssertEqual(t, 0, len(friends))
+	}
+}
+
+func setupTestDataForGetFriendNetwork(db *sql.DB) (int64, func(), error) {
+	// UserRepositoryを使ってテストデータを作成する
+	userRepo := repository.NewUserRepository(db)
+
+	users := make(map[string]int64)
+	for _, name := range []string{"alice", "bob", "charlie", "david", "eve", "frank"} {
+		user, err := userRepo.CreateUser(name)
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to create %s: %v", name, err)
+		}
+		users[name] = user.ID
+	}
+
+	// alice から bob, charlie を経由して david、さらに eve, frank へと続く友達関係を作る
+	query := "INSERT INTO friend_link (user1_id, user2_id) VALUES (?, ?), (?, ?)"
+	pairs := [][2]string{
+		{"alice", "bob"},
+		{"alice", "charlie"},
+		{"bob", "david"},
+		{"charlie", "david"},
+		{"david", "eve"},
+		{"eve", "frank"},
+	}
+	for _, pair := range pairs {
+		if _, err := db.Exec(query, users[pair[0]], users[pair[1]], users[pair[1]], users[pair[0]]); err != nil {
+			return 0, nil, fmt.Errorf("failed to create friend: %v", err)
+		}
+	}
+
+	// テストデータの削除用関数
+	cleanupFunc := func() {
+		// テーブルのデータを全削除する
+		for _, table := range []string{"block_list", "friend_link", "users"} {
+			if _, err := db.Exec("DELETE FROM " + table); err != nil {
+				panic(err)
+			}
+		}
+	}
+
+	return users["alice"], cleanupFunc, nil
+}
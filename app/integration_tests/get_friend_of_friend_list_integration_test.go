@@ -3,7 +3,6 @@ package integration_tests
 
 import (
 	"database/sql"
-	"encoding/json"
 	"fmt"
 	"io"
 	"minimal_sns_app/configs"
@@ -74,8 +73,7 @@ func TestGetFriendOfFriendListIntegration(t *testing.T) {
 
 	// レスポンスボディを期待する構造体にデコード
 	var gotFriends []models.Friend
-	err = json.Unmarshal(body, &gotFriends)
-	testhelpers.AssertNoError(t, err)
+	testhelpers.DecodeResponse(t, body, &gotFriends)
 	// Responseの中身からnameだけを取り出す
 	var gotFriendsName []string
 	for _, friend := range gotFriends {
@@ -87,6 +85,11 @@ func TestGetFriendOfFriendListIntegration(t *testing.T) {
 
 	// 取得したフレンドリストと期待するリストを比較
 	testhelpers.AssertDeepEqual(t, expectedFriends, gotFriendsName)
+
+	// David との共通の友達は Bob のみ
+	if len(gotFriends) == 1 {
+		testhelpers.AssertEqual(t, 1, gotFriends[0].MutualFriendCount)
+	}
 }
 
 func setupTest2hopData(db *sql.DB) (targetID int64, cleanupFunc func() error, err error) {
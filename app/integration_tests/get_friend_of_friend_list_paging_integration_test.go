@@ -1,7 +1,6 @@
 package integration_tests
 
 import (
-	"encoding/json"
 	"fmt"
 	"io"
 	"minimal_sns_app/configs"
@@ -80,15 +79,9 @@ func TestGetFriendOfFriendListPagingIntegration(t *testing.T) {
 	}
 
 	var result []models.User
-	err = json.Unmarshal(bodyBytes, &result)
-	if err != nil {
-		t.Fatalf("failed to unmarshal response body: %v", err)
-	}
+	envelope := testhelpers.DecodeResponse(t, bodyBytes, &result)
 	var result2 []models.User
-	err = json.Unmarshal(bodyBytes2, &result2)
-	if err != nil {
-		t.Fatalf("failed to unmarshal response body: %v", err)
-	}
+	testhelpers.DecodeResponse(t, bodyBytes2, &result2)
 
 	// nameだけを取り出す
 	var gotFriendsName []string
@@ -110,6 +103,39 @@ func TestGetFriendOfFriendListPagingIntegration(t *testing.T) {
 	// 取得したフレンドリストと期待するリストを比較
 	testhelpers.AssertDeepEqual(t, expectedFriends, gotFriendsName)
 	testhelpers.AssertDeepEqual(t, expectedFriends2, gotFriendsName2)
+
+	// meta には全件数とページ情報が入る
+	testhelpers.AssertNotNil(t, envelope.Meta)
+	if envelope.Meta != nil && envelope.Meta.Total != nil {
+		testhelpers.AssertEqual(t, 7, *envelope.Meta.Total)
+		testhelpers.AssertEqual(t, 5, envelope.Meta.Limit)
+		testhelpers.AssertEqual(t, 1, envelope.Meta.Page)
+	}
+
+	// 大きすぎる limit は上限に抑えられ、大きすぎる page は 400 になる
+	resp3, err := client.Get(fmt.Sprintf("%s/get_friend_of_friend_list_paging?id=%d&limit=9223372036854775807&page=1", ts.URL, targetID))
+	if err != nil {
+		t.Fatalf("failed to execute request: %v", err)
+	}
+	defer resp3.Body.Close()
+	testhelpers.AssertEqual(t, http.StatusOK, resp3.StatusCode)
+	bodyBytes3, err := io.ReadAll(resp3.Body)
+	if err != nil {
+		t.Fatalf("failed to read response body: %v", err)
+	}
+	var result3 []models.User
+	envelope3 := testhelpers.DecodeResponse(t, bodyBytes3, &result3)
+	testhelpers.AssertEqual(t, 7, len(result3))
+	if envelope3.Meta != nil {
+		testhelpers.AssertEqual(t, 100, envelope3.Meta.Limit)
+	}
+
+	resp4, err := client.Get(fmt.Sprintf("%s/get_friend_of_friend_list_paging?id=%d&limit=100&page=9223372036854775807", ts.URL, targetID))
+	if err != nil {
+		t.Fatalf("failed to execute request: %v", err)
+	}
+	resp4.Body.Close()
+	testhelpers.AssertEqual(t, http.StatusBadRequest, resp4.StatusCode)
 }
 
 func setupTestDataForGetFriendOfFriendListPaging(db *sql.DB) (int64, func(), error) {
@@ -0,0 +1,135 @@
+package integration_tests
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"minimal_sns_app/configs"
+	"minimal_sns_app/domain/models"
+	"minimal_sns_app/handlers"
+	"minimal_sns_app/logutils"
+	"minimal_sns_app/repository"
+	"minimal_sns_app/testhelpers"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/labstack/echo/v4"
+)
+
+// テスト対象 /get_friend_path?id=1&target_id=4&max_depth=6 response: 200 [{"id":1,"name":"alice"},...] or 404 "Friend path not found"
+func TestGetFriendPathIntegration(t *testing.T) {
+	logutils.InitLog()
+	// 初期設定
+	e := echo.New()
+	conf := configs.Get()
+	db, err := sql.Open(conf.DB.Driver, conf.DB.DataSource)
+	if err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	// リポジトリとハンドラーの設定
+	friendRepo := repository.NewFriendRepository(db)
+	friendHandler := handlers.NewFriendHandler(friendRepo)
+	friendHandler.RegisterRoutes(e)
+
+	// テストサーバーの設定
+	ts := httptest.NewServer(e)
+	defer ts.Close()
+
+	users, cleanupFunc, err := setupTestDataForGetFriendPath(db)
+	if err != nil {
+		t.Fatalf("failed to setup test data: %v", err)
+	}
+	defer cleanupFunc()
+
+	client := &http.Client{}
+
+	// alice から eve までの最短経路は alice -> bob -> david -> eve
+	resp, err := client.Get(fmt.Sprintf("%s/get_friend_path?id=%d&target_id=%d", ts.URL, users["alice"], users["eve"]))
+	if err != nil {
+		t.Fatalf("failed to execute request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	testhelpers.AssertEqual(t, http.StatusOK, resp.StatusCode)
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read response body: %v", err)
+	}
+
+	var path []models.Friend
+	testhelpers.DecodeResponse(t, bodyBytes, &path)
+
+	var gotPathName []string
+	for _, friend := range path {
+		gotPathName = append(gotPathName, friend.Name)
+	}
+	testhelpers.AssertDeepEqual(t, []string{"alice", "bob", "david", "eve"}, gotPathName)
+
+	// max_depth が足りない場合は見つからない
+	resp2, err := client.Get(fmt.Sprintf("%s/get_friend_path?id=%d&target_id=%d&max_depth=2", ts.URL, users["alice"], users["eve"]))
+	if err != nil {
+		t.Fatalf("failed to execute request: %v", err)
+	}
+	defer resp2.Body.Close()
+
+	testhelpers.AssertEqual(t, http.StatusNotFound, resp2.StatusCode)
+
+	// david が alice をブロックすると、david へも david を経由した eve へも経路がなくなる
+	if _, err := db.Exec("INSERT INTO block_list (user1_id, user2_id) VALUES (?, ?)", users["david"], users["alice"]); err != nil {
+		t.Fatalf("failed to insert block: %v", err)
+	}
+	for _, target := range []string{"david", "eve"} {
+		resp, err := client.Get(fmt.Sprintf("%s/get_friend_path?id=%d&target_id=%d", ts.URL, users["alice"], users[target]))
+		if err != nil {
+			t.Fatalf("failed to execute request: %v", err)
+		}
+		resp.Body.Close()
+		testhelpers.AssertEqual(t, http.StatusNotFound, resp.StatusCode)
+	}
+}
+
+func setupTestDataForGetFriendPath(db *sql.DB) (map[string]int64, func(), error) {
+	// UserRepositoryを使ってテストデータを作成する
+	userRepo := repository.NewUserRepository(db)
+
+	users := make(map[string]int64)
+	for _, name := range []string{"alice", "bob", "charlie", "david", "eve", "frank"} {
+		user, err := userRepo.CreateUser(name)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create %s: %v", name, err)
+		}
+		users[name] = user.ID
+	}
+
+	// alice - bob - david - eve の経路と、行き止まりの alice - charlie - frank の経路を作る
+	query := "INSERT INTO friend_link (user1_id, user2_id) VALUES (?, ?), (?, ?)"
+	pairs := [][2]string{
+		{"alice", "bob"},
+		{"alice", "charlie"},
+		{"bob", "david"},
+		{"charlie", "frank"},
+		{"david", "eve"},
+	}
+	for _, pair := range pairs {
+		if _, err := db.Exec(query, users[pair[0]], users[pair[1]], users[pair[1]], users[pair[0]]); err != nil {
+			return nil, nil, fmt.Errorf("failed to create friend: %v", err)
+		}
+	}
+
+	// テストデータの削除用関数
+	cleanupFunc := func() {
+		// テーブルのデータを全削除する
+		for _, table := range []string{"block_list", "friend_link", "users"} {
+			if _, err := db.Exec("DELETE FROM " + table); err != nil {
+				panic(err)
+			}
+		}
+	}
+
+	return users, cleanupFunc, nil
+}
@@ -0,0 +1,136 @@
+package integration_tests
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"minimal_sns_app/configs"
+	"minimal_sns_app/domain/models"
+	"minimal_sns_app/handlers"
+	"minimal_sns_app/logutils"
+	"minimal_sns_app/repository"
+	"minimal_sns_app/testhelpers"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/labstack/echo/v4"
+)
+
+// テスト対象 /get_friend_recommendation_list?id=1&limit=10&page=1 response: 200 [{"id":4,"name":"david","mutual_friend_count":2}] or 500 "Failed to get friend recommendations"
+func TestGetFriendRecommendationListIntegration(t *testing.T) {
+	logutils.InitLog()
+	// 初期設定
+	e := echo.New()
+	conf := configs.Get()
+	db, err := sql.Open(conf.DB.Driver, conf.DB.DataSource)
+	if err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	// リポジトリとハンドラーの設定
+	friendRepo := repository.NewFriendRepository(db)
+	friendHandler := handlers.NewFriendHandler(friendRepo)
+	friendHandler.RegisterRoutes(e)
+
+	// テストサーバーの設定
+	ts := httptest.NewServer(e)
+	defer ts.Close()
+
+	targetID, cleanupFunc, err := setupTestDataForGetFriendRecommendationList(db)
+	if err != nil {
+		t.Fatalf("failed to setup test data: %v", err)
+	}
+	defer cleanupFunc()
+
+	client := &http.Client{}
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/get_friend_recommendation_list?id=%d&limit=10&page=1", ts.URL, targetID), nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("failed to execute request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	testhelpers.AssertEqual(t, http.StatusOK, resp.StatusCode)
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read response body: %v", err)
+	}
+
+	var friends []models.Friend
+	testhelpers.DecodeResponse(t, bodyBytes, &friends)
+
+	// name と共通の友達の数を取り出す
+	var gotFriendsName []string
+	var gotMutualCounts []int
+	for _, friend := range friends {
+		gotFriendsName = append(gotFriendsName, friend.Name)
+		gotMutualCounts = append(gotMutualCounts, friend.MutualFriendCount)
+	}
+
+	// eve は申請中、frank は alice をブロックしているので除外され、共通の友達が多い順に並ぶ
+	testhelpers.AssertDeepEqual(t, []string{"david", "george"}, gotFriendsName)
+	testhelpers.AssertDeepEqual(t, []int{2, 1}, gotMutualCounts)
+}
+
+func setupTestDataForGetFriendRecommendationList(db *sql.DB) (int64, func(), error) {
+	// UserRepositoryを使ってテストデータを作成する
+	userRepo := repository.NewUserRepository(db)
+
+	users := make(map[string]int64)
+	for _, name := range []string{"alice", "bob", "charlie", "george", "david", "eve", "frank"} {
+		user, err := userRepo.CreateUser(name)
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to create %s: %v", name, err)
+		}
+		users[name] = user.ID
+	}
+
+	// 双方向の友達関係を作成する
+	query := "INSERT INTO friend_link (user1_id, user2_id) VALUES (?, ?), (?, ?)"
+	pairs := [][2]string{
+		{"alice", "bob"},
+		{"alice", "charlie"},
+		{"bob", "david"},
+		{"bob", "eve"},
+		{"bob", "frank"},
+		{"charlie", "david"},
+		{"charlie", "eve"},
+		{"charlie", "george"},
+	}
+	for _, pair := range pairs {
+		if _, err := db.Exec(query, users[pair[0]], users[pair[1]], users[pair[1]], users[pair[0]]); err != nil {
+			return 0, nil, fmt.Errorf("failed to create friend: %v", err)
+		}
+	}
+
+	// alice から eve への申請中リクエスト
+	friendRepo := repository.NewFriendRepository(db)
+	if _, err := friendRepo.RequestFriend(users["alice"], users["eve"], ""); err != nil {
+		return 0, nil, fmt.Errorf("failed to create friend request: %v", err)
+	}
+
+	// frank が alice をブロック
+	if _, err := db.Exec("INSERT INTO block_list (user1_id, user2_id) VALUES (?, ?)", users["frank"], users["alice"]); err != nil {
+		return 0, nil, fmt.Errorf("failed to create block: %v", err)
+	}
+
+	// テストデータの削除用関数
+	cleanupFunc := func() {
+		// テーブルのデータを全削除する
+		for _, table := range []string{"friend_requests", "block_list", "friend_link", "users"} {
+			if _, err := db.Exec("DELETE FROM " + table); err != nil {
+				panic(err)
+			}
+		}
+	}
+
+	return users["alice"], cleanupFunc, nil
+}
@@ -0,0 +1,110 @@
+package integration_tests
+
+import (
+	"fmt"
+	"io"
+	"minimal_sns_app/configs"
+	"minimal_sns_app/domain/models"
+	"minimal_sns_app/handlers"
+	"minimal_sns_app/repository"
+	"minimal_sns_app/testhelpers"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"database/sql"
+
+	"github.com/labstack/echo/v4"
+)
+
+// テスト対象 /get_friend_request_history?id=1&friend_id=2 response: 200 [{"id":1,"status":"declined","events":[...]}] or 500 "Failed to get friend request history"
+// 拒否された申請の再送信、クールダウン、遷移の記録をあわせて確認する
+func TestGetFriendRequestHistoryIntegration(t *testing.T) {
+	// 初期設定
+	e := echo.New()
+	e.HTTPErrorHandler = handlers.HTTPErrorHandler
+	conf := configs.Get()
+	db, err := sql.Open(conf.DB.Driver, conf.DB.DataSource)
+	if err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	// リポジトリとハンドラーの設定
+	friendRepo := repository.NewFriendRepository(db)
+	friendHandler := handlers.NewFriendHandler(friendRepo)
+	friendHandler.RegisterRoutes(e)
+
+	// テストサーバーの設定
+	ts := httptest.NewServer(e)
+	defer ts.Close()
+
+	userRepo := repository.NewUserRepository(db)
+	alice, err := userRepo.CreateUser("alice")
+	if err != nil {
+		t.Fatalf("failed to create user1: %v", err)
+	}
+	bob, err := userRepo.CreateUser("bob")
+	if err != nil {
+		t.Fatalf("failed to create user2: %v", err)
+	}
+	defer func() {
+		// テーブルのデータを全削除する（申請と履歴は users の削除で連鎖して消える）
+		if _, err := db.Exec("DELETE FROM users"); err != nil {
+			panic(err)
+		}
+	}()
+
+	client := &http.Client{}
+	post := func(path string, userID int64, friendID int64) int {
+		resp, err := client.Post(fmt.Sprintf("%s/%s?id=%d&friend_id=%d", ts.URL, path, userID, friendID), "", nil)
+		if err != nil {
+			t.Fatalf("failed to execute request: %v", err)
+		}
+		defer resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// alice が申請し、bob が拒否する
+	testhelpers.AssertEqual(t, http.StatusOK, post("request_friend", alice.ID, bob.ID))
+	testhelpers.AssertEqual(t, http.StatusOK, post("decline_friend", bob.ID, alice.ID))
+
+	// 拒否された直後の再申請はクールダウン中のため 429 になる
+	testhelpers.AssertEqual(t, http.StatusTooManyRequests, post("request_friend", alice.ID, bob.ID))
+
+	// クールダウンが過ぎたことにする
+	if _, err := db.Exec("UPDATE friend_requests SET updated_at = NOW() - INTERVAL 2 DAY WHERE status = 'declined'"); err != nil {
+		t.Fatalf("failed to update friend request: %v", err)
+	}
+
+	// 再申請と二度目の拒否ができる
+	testhelpers.AssertEqual(t, http.StatusOK, post("request_friend", alice.ID, bob.ID))
+	testhelpers.AssertEqual(t, http.StatusOK, post("decline_friend", bob.ID, alice.ID))
+
+	// 履歴には2件の申請がそれぞれの遷移とともに古い順に並ぶ
+	resp, err := client.Get(fmt.Sprintf("%s/get_friend_request_history?id=%d&friend_id=%d", ts.URL, bob.ID, alice.ID))
+	if err != nil {
+		t.Fatalf("failed to execute request: %v", err)
+	}
+	defer resp.Body.Close()
+	testhelpers.AssertEqual(t, http.StatusOK, resp.StatusCode)
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read response body: %v", err)
+	}
+	var history []models.FriendRequest
+	testhelpers.DecodeResponse(t, bodyBytes, &history)
+
+	testhelpers.AssertEqual(t, 2, len(history))
+	for _, request := range history {
+		testhelpers.AssertEqual(t, alice.ID, request.RequesterID)
+		testhelpers.AssertEqual(t, bob.ID, request.RequestedID)
+		testhelpers.AssertEqual(t, models.FriendRequestDeclined, request.Status)
+		testhelpers.AssertEqual(t, 2, len(request.Events))
+		if len(request.Events) == 2 {
+			testhelpers.AssertEqual(t, models.FriendRequestPending, request.Events[0].Status)
+			testhelpers.AssertEqual(t, models.FriendRequestDeclined, request.Events[1].Status)
+		}
+	}
+}
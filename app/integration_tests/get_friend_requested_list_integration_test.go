@@ -1,7 +1,6 @@
 package integration_tests
 
 import (
-	"encoding/json"
 	"fmt"
 	"io"
 	"minimal_sns_app/configs"
@@ -64,10 +63,7 @@ func TestGetFriendRequestedListIntegration(t *testing.T) {
 	}
 
 	var users []models.User
-	err = json.Unmarshal(bodyBytes, &users)
-	if err != nil {
-		t.Fatalf("failed to unmarshal response body: %v", err)
-	}
+	testhelpers.DecodeResponse(t, bodyBytes, &users)
 
 	testhelpers.AssertEqual(t, 1, len(users))
 	testhelpers.AssertEqual(t, bobID, users[0].ID)
@@ -89,7 +85,7 @@ func setupTestDataForGetFriendRequestedList(db *sql.DB) (int64, int64, func(), e
 
 	// FriendRepositoryを使ってテストデータを作成する
 	friendRepo := repository.NewFriendRepository(db)
-	err = friendRepo.RequestFriend(alice.ID, bob.ID)
+	_, err = friendRepo.RequestFriend(alice.ID, bob.ID, "")
 	if err != nil {
 		return 0, 0, nil, fmt.Errorf("failed to create friend request: %v", err)
 	}
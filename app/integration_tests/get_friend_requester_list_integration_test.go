@@ -1,7 +1,6 @@
 package integration_tests
 
 import (
-	"encoding/json"
 	"fmt"
 	"io"
 	"minimal_sns_app/configs"
@@ -18,7 +17,7 @@ import (
 	"github.com/labstack/echo/v4"
 )
 
-// テスト対象 /get_friend_requester_list?id=1 response: 200 [{"id":1,"name":"alice"}] or 500 "Failed to get friend requesters list"
+// テスト対象 /get_friend_requester_list?id=1&status=pending response: 200 [{"id":1,"name":"alice","request_id":3,"status":"pending","created_at":"..."}] or 400 "Invalid status" or 500 "Failed to get friend requesters list"
 func TestGetFriendRequesterListIntegration(t *testing.T) {
 	// 初期設定
 	e := echo.New()
@@ -64,14 +63,56 @@ func TestGetFriendRequesterListIntegration(t *testing.T) {
 	}
 
 	var users []models.User
-	err = json.Unmarshal(bodyBytes, &users)
-	if err != nil {
-		t.Fatalf("failed to unmarshal response body: %v", err)
-	}
+	testhelpers.DecodeResponse(t, bodyBytes, &users)
 
 	testhelpers.AssertEqual(t, 1, len(users))
 	testhelpers.AssertEqual(t, aliceID, users[0].ID)
 	testhelpers.AssertEqual(t, "alice", users[0].Name)
+
+	// 申請のID、ステータス、作成日時も返る
+	var requesters []models.FriendRequestUser
+	testhelpers.DecodeResponse(t, bodyBytes, &requesters)
+	testhelpers.AssertEqual(t, models.FriendRequestPending, requesters[0].Status)
+	testhelpers.AssertNotEqual(t, int64(0), requesters[0].RequestID)
+	testhelpers.AssertEqual(t, false, requesters[0].CreatedAt.IsZero())
+
+	// 拒否した申請は既定の一覧から消え、status=declined で取得できる
+	if err := friendRepo.DeclineFriend(bobID, aliceID); err != nil {
+		t.Fatalf("failed to decline friend request: %v", err)
+	}
+
+	tests := []struct {
+		status        string
+		expectedCount int
+	}{
+		{"", 0},
+		{"declined", 1},
+		{"accepted", 0},
+	}
+	for _, tt := range tests {
+		resp, err := client.Get(fmt.Sprintf("%s/get_friend_requester_list?id=%d&status=%s", ts.URL, bobID, tt.status))
+		if err != nil {
+			t.Fatalf("failed to execute request: %v", err)
+		}
+		bodyBytes, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("failed to read response body: %v", err)
+		}
+		testhelpers.AssertEqual(t, http.StatusOK, resp.StatusCode)
+
+		var filtered []models.FriendRequestUser
+		testhelpers.DecodeResponse(t, bodyBytes, &filtered)
+		testhelpers.AssertEqual(t, tt.expectedCount, len(filtered))
+	}
+
+	// 不正なステータスは 400 になる
+	resp, err = client.Get(fmt.Sprintf("%s/get_friend_requester_list?id=%d&status=unknown", ts.URL, bobID))
+	if err != nil {
+		t.Fatalf("failed to execute request: %v", err)
+	}
+	resp.Body.Close()
+	testhelpers.AssertEqual(t, http.StatusBadRequest, resp.StatusCode)
 }
 
 func setupTestDataForGetFriendRequesterList(db *sql.DB) (int64, int64, func(), error) {
@@ -89,7 +130,7 @@ func setupTestDataForGetFriendRequesterList(db *sql.DB) (int64, int64, func(), e
 
 	// FriendRepositoryを使ってテストデータを作成する
 	friendRepo := repository.NewFriendRepository(db)
-	err = friendRepo.RequestFriend(alice.ID, bob.ID)
+	_, err = friendRepo.RequestFriend(alice.ID, bob.ID, "")
 	if err != nil {
 		return 0, 0, nil, fmt.Errorf("failed to create friend request: %v", err)
 	}
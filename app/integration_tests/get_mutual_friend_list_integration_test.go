@@ -0,0 +1,146 @@
+package integration_tests
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"minimal_sns_app/configs"
+	"minimal_sns_app/domain/models"
+	"minimal_sns_app/handlers"
+	"minimal_sns_app/logutils"
+	"minimal_sns_app/repository"
+	"minimal_sns_app/testhelpers"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/labstack/echo/v4"
+)
+
+// テスト対象 /get_mutual_friend_list?id=1&other_id=2 response: 200 [{"id":3,"name":"charlie"}] or 500 "Failed to get mutual friends"
+func TestGetMutualFriendListIntegration(t *testing.T) {
+	logutils.InitLog()
+	// 初期設定
+	e := echo.New()
+	conf := configs.Get()
+	db, err := sql.Open(conf.DB.Driver, conf.DB.DataSource)
+	if err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	// リポジトリとハンドラーの設定
+	friendRepo := repository.NewFriendRepository(db)
+	friendHandler := handlers.NewFriendHandler(friendRepo)
+	friendHandler.RegisterRoutes(e)
+
+	// テストサーバーの設定
+	ts := httptest.NewServer(e)
+	defer ts.Close()
+
+	aliceID, bobID, cleanupFunc, err := setupTestDataForGetMutualFriendList(db)
+	if err != nil {
+		t.Fatalf("failed to setup test data: %v", err)
+	}
+	defer cleanupFunc()
+
+	client := &http.Client{}
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/get_mutual_friend_list?id=%d&other_id=%d", ts.URL, aliceID, bobID), nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("failed to execute request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	testhelpers.AssertEqual(t, http.StatusOK, resp.StatusCode)
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read response body: %v", err)
+	}
+
+	var friends []models.Friend
+	testhelpers.DecodeResponse(t, bodyBytes, &friends)
+
+	// nameだけを取り出す
+	var gotFriendsName []string
+	for _, friend := range friends {
+		gotFriendsName = append(gotFriendsName, friend.Name)
+	}
+
+	// alice と bob の共通の友達は charlie と david のみ
+	testhelpers.AssertDeepEqual(t, []string{"charlie", "david"}, gotFriendsName)
+
+	// bob が alice をブロックすると、どちらから見ても共通の友達は返らない
+	if _, err := db.Exec("INSERT INTO block_list (user1_id, user2_id) VALUES (?, ?)", bobID, aliceID); err != nil {
+		t.Fatalf("failed to insert block: %v", err)
+	}
+	for _, pair := range [][2]int64{{aliceID, bobID}, {bobID, aliceID}} {
+		resp, err := client.Get(fmt.Sprintf("%s/get_mutual_friend_list?id=%d&other_id=%d", ts.URL, pair[0], pair[1]))
+		if err != nil {
+			t.Fatalf("failed to execute request: %v", err)
+		}
+		bodyBytes, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("failed to read response body: %v", err)
+		}
+		testhelpers.AssertEqual(t, http.StatusOK, resp.StatusCode)
+
+		var blockedFriends []models.Friend
+		testhelpers.DecodeResponse(t, bodyBytes, &blockedFriends)
+		testhelpers.AssertEqual(t, 0, len(blockedFriends))
+	}
+}
+
+func setupTestDataForGetMutualFriendList(db *sql.DB) (int64, int64, func(), error) {
+	// UserRepositoryを使ってテストデータを作成する
+	userRepo := repository.NewUserRepository(db)
+
+	var createdUsers []models.User
+	for _, name := range []string{"alice", "bob", "charlie", "david", "eve"} {
+		user, err := userRepo.CreateUser(name)
+		if err != nil {
+			return 0, 0, nil, fmt.Errorf("failed to create %s: %v", name, err)
+		}
+		createdUsers = append(createdUsers, *user)
+	}
+	alice, bob, charlie, david, eve := createdUsers[0], createdUsers[1], createdUsers[2], createdUsers[3], createdUsers[4]
+
+	// alice: charlie, david, eve と友達 / bob: charlie, david と友達
+	query := "INSERT INTO friend_link (user1_id, user2_id) VALUES (?, ?), (?, ?)"
+	pairs := [][2]int64{
+		{alice.ID, charlie.ID},
+		{alice.ID, david.ID},
+		{alice.ID, eve.ID},
+		{bob.ID, charlie.ID},
+		{bob.ID, david.ID},
+	}
+	for _, pair := range pairs {
+		if _, err := db.Exec(query, pair[0], pair[1], pair[1], pair[0]); err != nil {
+			return 0, 0, nil, fmt.Errorf("failed to create friend: %v", err)
+		}
+	}
+
+	// テストデータの削除用関数
+	cleanupFunc := func() {
+		// テーブルのデータを全削除する
+		query := "DELETE FROM friend_link"
+		_, err := db.Exec(query)
+		if err != nil {
+			panic(err)
+		}
+		query = "DELETE FROM users"
+		_, err = db.Exec(query)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	return alice.ID, bob.ID, cleanupFunc, nil
+}
@@ -3,7 +3,6 @@ package integration_tests
 
 import (
 	"database/sql"
-	"encoding/json"
 	"fmt"
 	"io"
 	"minimal_sns_app/configs"
@@ -38,7 +37,7 @@ func TestGetUserIntegration(t *testing.T) {
 
 	e := echo.New()
 	userRepo := repository.NewUserRepository(db)
-	userHandler := handlers.NewUserHandler(userRepo)
+	userHandler := handlers.NewUserHandler(userRepo, conf.User)
 	userHandler.RegisterRoutes(e)
 
 	ts := httptest.NewServer(e)
@@ -62,14 +61,13 @@ func TestGetUserIntegration(t *testing.T) {
 		t.Fatalf("failed to read response body: %v", err)
 	}
 	var actualUser models.User
-	if err := json.Unmarshal(bodyBytes, &actualUser); err != nil {
-		t.Fatalf("failed to unmarshal response body: %v", err)
-	}
-	var expectedResponseBody = models.User{
-		ID:   createdUserID,
-		Name: "testUser",
-	}
-	testhelpers.AssertEqual(t, actualUser, expectedResponseBody)
+	testhelpers.DecodeResponse(t, bodyBytes, &actualUser)
+	testhelpers.AssertEqual(t, createdUserID, actualUser.ID)
+	testhelpers.AssertEqual(t, "testUser", actualUser.Name)
+	// プロフィールは未設定で、作成日時が入っている
+	testhelpers.AssertEqual(t, "", actualUser.DisplayName)
+	testhelpers.AssertEqual(t, "", actualUser.Birthday)
+	testhelpers.AssertEqual(t, false, actualUser.CreatedAt.IsZero())
 }
 
 func setupTestDataForGetUser(db *sql.DB) (createdUserID int64, cleanupFunc func(), err error) {
@@ -0,0 +1,104 @@
+package integration_tests
+
+import (
+	"fmt"
+	"io"
+	"minimal_sns_app/configs"
+	"minimal_sns_app/domain/models"
+	"minimal_sns_app/handlers"
+	"minimal_sns_app/repository"
+	"minimal_sns_app/testhelpers"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"database/sql"
+
+	"github.com/labstack/echo/v4"
+)
+
+// テスト対象 /users?ids=1,2,3 response: 200 {"users":[...],"missing_ids":[2]} or 400 "Invalid user ids" or 500 "Failed to get users"
+// あわせて /user?name=alice で名前から取得できることを確認する
+func TestGetUsersIntegration(t *testing.T) {
+	// 初期設定
+	e := echo.New()
+	e.HTTPErrorHandler = handlers.HTTPErrorHandler
+	conf := configs.Get()
+	db, err := sql.Open(conf.DB.Driver, conf.DB.DataSource)
+	if err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	// リポジトリとハンドラーの設定
+	userRepo := repository.NewUserRepository(db)
+	userHandler := handlers.NewUserHandler(userRepo, conf.User)
+	userHandler.RegisterRoutes(e)
+
+	// テストサーバーの設定
+	ts := httptest.NewServer(e)
+	defer ts.Close()
+
+	alice, err := userRepo.CreateUser("alice")
+	if err != nil {
+		t.Fatalf("failed to create user1: %v", err)
+	}
+	bob, err := userRepo.CreateUser("bob")
+	if err != nil {
+		t.Fatalf("failed to create user2: %v", err)
+	}
+	defer func() {
+		// テーブルのデータを全削除する
+		if _, err := db.Exec("DELETE FROM users"); err != nil {
+			panic(err)
+		}
+	}()
+
+	client := &http.Client{}
+	get := func(path string) (int, []byte) {
+		resp, err := client.Get(ts.URL + path)
+		if err != nil {
+			t.Fatalf("failed to execute request: %v", err)
+		}
+		defer resp.Body.Close()
+		bodyBytes, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("failed to read response body: %v", err)
+		}
+		return resp.StatusCode, bodyBytes
+	}
+
+	// 名前で取得できる
+	status, bodyBytes := get("/user?name=bob")
+	testhelpers.AssertEqual(t, http.StatusOK, status)
+	var user models.User
+	testhelpers.DecodeResponse(t, bodyBytes, &user)
+	testhelpers.AssertEqual(t, bob.ID, user.ID)
+
+	// 存在しない名前は 404、id と name の両方または一方もない指定は 400 になる
+	status, _ = get("/user?name=nobody")
+	testhelpers.AssertEqual(t, http.StatusNotFound, status)
+	status, _ = get(fmt.Sprintf("/user?id=%d&name=bob", alice.ID))
+	testhelpers.AssertEqual(t, http.StatusBadRequest, status)
+	status, _ = get("/user")
+	testhelpers.AssertEqual(t, http.StatusBadRequest, status)
+
+	// 指定した順に、重複を除いて返り、見つからない ID が別に返る
+	missingID := bob.ID + 1000
+	status, bodyBytes = get(fmt.Sprintf("/users?ids=%d,%d,%d,%d", bob.ID, missingID, alice.ID, bob.ID))
+	testhelpers.AssertEqual(t, http.StatusOK, status)
+	var batch models.UserBatch
+	testhelpers.DecodeResponse(t, bodyBytes, &batch)
+	testhelpers.AssertEqual(t, 2, len(batch.Users))
+	if len(batch.Users) == 2 {
+		testhelpers.AssertEqual(t, bob.ID, batch.Users[0].ID)
+		testhelpers.AssertEqual(t, alice.ID, batch.Users[1].ID)
+	}
+	testhelpers.AssertDeepEqual(t, []int64{missingID}, batch.MissingIDs)
+
+	// 不正な ID の一覧は 400 になる
+	for _, ids := range []string{"", "1,a", "1,,2"} {
+		status, _ = get("/users?ids=" + ids)
+		testhelpers.AssertEqual(t, http.StatusBadRequest, status)
+	}
+}
@@ -0,0 +1,83 @@
+package integration_tests
+
+import (
+	"io"
+	"minimal_sns_app/handlers"
+	"minimal_sns_app/logutils"
+	"minimal_sns_app/repository"
+	"minimal_sns_app/testhelpers"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+// テスト対象 HTTPErrorHandler がルーターのエラーやパニックも含めて同じ形式で、Accept-Language に合わせた言語で返すこと
+func TestHTTPErrorHandlerIntegration(t *testing.T) {
+	logutils.InitLog()
+	// 初期設定 DBに触れる前にエラーになるリクエストだけを送る
+	e := echo.New()
+	e.HTTPErrorHandler = handlers.HTTPErrorHandler
+	e.Use(logutils.RequestIDMiddleware)
+	e.Use(logutils.RecoverMiddleware)
+
+	friendHandler := handlers.NewFriendHandler(repository.NewFriendRepository(nil))
+	friendHandler.RegisterRoutes(e)
+	e.GET("/panic", func(c echo.Context) error {
+		panic("test panic")
+	})
+
+	// テストサーバーの設定
+	ts := httptest.NewServer(e)
+	defer ts.Close()
+
+	tests := []struct {
+		name            string
+		method          string
+		path            string
+		acceptLanguage  string
+		expectedStatus  int
+		expectedCode    string
+		expectedMessage string
+	}{
+		{"invalid parameter in english", "GET", "/get_friend_list?id=abc", "en-US,en;q=0.9", http.StatusBadRequest, "invalid_parameter", "Invalid user id"},
+		{"invalid parameter in japanese", "GET", "/get_friend_list?id=abc", "ja,en-US;q=0.8", http.StatusBadRequest, "invalid_parameter", "ユーザーIDが不正です"},
+		{"english preferred over japanese", "GET", "/get_friend_list?id=abc", "ja;q=0.5,en;q=0.9", http.StatusBadRequest, "invalid_parameter", "Invalid user id"},
+		{"unknown route", "GET", "/unknown", "ja", http.StatusNotFound, "not_found", "見つかりません"},
+		{"method not allowed", "PUT", "/get_friend_list?id=1", "", http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed"},
+		{"panic", "GET", "/panic", "", http.StatusInternalServerError, "internal_error", "Internal server error"},
+	}
+
+	client := &http.Client{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, ts.URL+tt.path, nil)
+			if err != nil {
+				t.Fatalf("failed to create request: %v", err)
+			}
+			req.Header.Set("Accept-Language", tt.acceptLanguage)
+
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("failed to execute request: %v", err)
+			}
+			defer resp.Body.Close()
+
+			testhelpers.AssertEqual(t, tt.expectedStatus, resp.StatusCode)
+
+			bodyBytes, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("failed to read response body: %v", err)
+			}
+			envelope := testhelpers.DecodeResponse(t, bodyBytes, nil)
+			if envelope.Error == nil {
+				t.Fatalf("expected an error in %s", string(bodyBytes))
+			}
+			testhelpers.AssertEqual(t, tt.expectedCode, envelope.Error.Code)
+			testhelpers.AssertEqual(t, tt.expectedMessage, envelope.Error.Message)
+			testhelpers.AssertEqual(t, resp.Header.Get("X-Request-ID"), envelope.Error.RequestID)
+			testhelpers.AssertNotEqual(t, "", envelope.Error.RequestID)
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package integration_tests
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"minimal_sns_app/configs"
+	"minimal_sns_app/handlers"
+	"minimal_sns_app/logutils"
+	"minimal_sns_app/repository"
+	"minimal_sns_app/testhelpers"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/labstack/echo/v4"
+)
+
+// テスト対象 友達申請まわりのエラーが適切なステータスコードとエラーコードで返ること
+func TestRequestFriendErrorIntegration(t *testing.T) {
+	logutils.InitLog()
+	// 初期設定
+	e := echo.New()
+	e.HTTPErrorHandler = handlers.HTTPErrorHandler
+	conf := configs.Get()
+	db, err := sql.Open(conf.DB.Driver, conf.DB.DataSource)
+	if err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	// リポジトリとハンドラーの設定
+	friendRepo := repository.NewFriendRepository(db)
+	friendHandler := handlers.NewFriendHandler(friendRepo)
+	friendHandler.RegisterRoutes(e)
+
+	// テストサーバーの設定
+	ts := httptest.NewServer(e)
+	defer ts.Close()
+
+	aliceID, bobID, cleanupFunc, err := setupTestDataForRequestFriendError(db)
+	if err != nil {
+		t.Fatalf("failed to setup test data: %v", err)
+	}
+	defer cleanupFunc()
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+		expectedCode   string
+	}{
+		{"duplicate request", fmt.Sprintf("/request_friend?id=%d&friend_id=%d", aliceID, bobID), http.StatusConflict, "duplicate"},
+		{"request to oneself", fmt.Sprintf("/request_friend?id=%d&friend_id=%d", aliceID, aliceID), http.StatusUnprocessableEntity, "self_reference"},
+		{"request to unknown user", fmt.Sprintf("/request_friend?id=%d&friend_id=%d", aliceID, bobID+1000), http.StatusNotFound, "not_found"},
+		{"accept unknown request", fmt.Sprintf("/accept_friend?id=%d&friend_id=%d", aliceID, bobID), http.StatusNotFound, "not_found"},
+		{"decline unknown request", fmt.Sprintf("/decline_friend?id=%d&friend_id=%d", aliceID, bobID), http.StatusNotFound, "not_found"},
+	}
+
+	client := &http.Client{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", ts.URL+tt.path, nil)
+			if err != nil {
+				t.Fatalf("failed to create request: %v", err)
+			}
+
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("failed to execute request: %v", err)
+			}
+			defer resp.Body.Close()
+
+			testhelpers.AssertEqual(t, tt.expectedStatus, resp.StatusCode)
+
+			bodyBytes, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("failed to read response body: %v", err)
+			}
+			envelope := testhelpers.DecodeResponse(t, bodyBytes, nil)
+			testhelpers.AssertNotNil(t, envelope.Error)
+			if envelope.Error != nil {
+				testhelpers.AssertEqual(t, tt.expectedCode, envelope.Error.Code)
+			}
+		})
+	}
+}
+
+func setupTestDataForRequestFriendError(db *sql.DB) (int64, int64, func(), error) {
+	// UserRepositoryを使ってテストデータを作成する
+	userRepo := repository.NewUserRepository(db)
+
+	alice, err := userRepo.CreateUser("alice") // テストデータの作成
+	if err != nil {
+		return 0, 0, nil, fmt.Errorf("failed to create user1: %v", err)
+	}
+	bob, err := userRepo.CreateUser("bob") // テストデータの作成
+	if err != nil {
+		return 0, 0, nil, fmt.Errorf("failed to create user2: %v", err)
+	}
+
+	// alice から bob への申請を作成しておく
+	friendRepo := repository.NewFriendRepository(db)
+	if _, err := friendRepo.RequestFriend(alice.ID, bob.ID, ""); err != nil {
+		return 0, 0, nil, fmt.Errorf("failed to create friend request: %v", err)
+	}
+
+	// テストデータの削除用関数
+	cleanupFunc := func() {
+		// テーブルのデータを全削除する
+		for _, table := range []string{"friend_requests", "users"} {
+			if _, err := db.Exec("DELETE FROM " + table); err != nil {
+				panic(err)
+			}
+		}
+	}
+
+	return alice.ID, bob.ID, cleanupFunc, nil
+}
@@ -1,14 +1,19 @@
 package integration_tests
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"minimal_sns_app/configs"
+	"minimal_sns_app/domain/models"
 	"minimal_sns_app/handlers"
 	"minimal_sns_app/repository"
 	"minimal_sns_app/testhelpers"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
 
 	"database/sql"
@@ -16,7 +21,7 @@ import (
 	"github.com/labstack/echo/v4"
 )
 
-// テスト対象 /request_friend?id=1&friend_id=2 response: 200 "Friend request sent" or 500 "Failed to send friend request"
+// テスト対象 /request_friend?id=1&friend_id=2 body: {"message":"hello"} response: 200 "Friend request sent" or 200 "Friend request accepted" or 400 "Invalid message" or 500 "Failed to send friend request"
 func TestRequestFriendIntegration(t *testing.T) {
 	// 初期設定
 	e := echo.New()
@@ -43,10 +48,23 @@ func TestRequestFriendIntegration(t *testing.T) {
 	defer cleanupFunc()
 
 	client := &http.Client{}
-	req, err := http.NewRequest("POST", fmt.Sprintf("%s/request_friend?id=%d&friend_id=%d", ts.URL, user1ID, user2ID), nil)
+	// 長すぎるメッセージや制御文字を含むメッセージは 400 になる
+	for _, message := range []string{strings.Repeat("あ", 201), "hello\u0007"} {
+		body, err := json.Marshal(map[string]string{"message": message})
+		testhelpers.AssertNoError(t, err)
+		resp, err := client.Post(fmt.Sprintf("%s/request_friend?id=%d&friend_id=%d", ts.URL, user1ID, user2ID), "application/json", bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("failed to execute request: %v", err)
+		}
+		resp.Body.Close()
+		testhelpers.AssertEqual(t, http.StatusBadRequest, resp.StatusCode)
+	}
+
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s/request_friend?id=%d&friend_id=%d", ts.URL, user1ID, user2ID), strings.NewReader(`{"message":"  大学で同じゼミだった user1 です  "}`))
 	if err != nil {
 		t.Fatalf("failed to create request: %v", err)
 	}
+	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := client.Do(req)
 	if err != nil {
@@ -60,7 +78,74 @@ func TestRequestFriendIntegration(t *testing.T) {
 		t.Fatalf("failed to read response body: %v", err)
 	}
 
-	testhelpers.AssertEqual(t, "Friend request sent", string(bodyBytes))
+	testhelpers.AssertEqual(t, "Friend request sent", testhelpers.DecodeMessage(t, bodyBytes))
+
+	// メッセージは前後の空白を除いて申請者一覧に返る
+	listResp, err := client.Get(fmt.Sprintf("%s/get_friend_requester_list?id=%d", ts.URL, user2ID))
+	if err != nil {
+		t.Fatalf("failed to execute request: %v", err)
+	}
+	defer listResp.Body.Close()
+	listBytes, err := io.ReadAll(listResp.Body)
+	if err != nil {
+		t.Fatalf("failed to read response body: %v", err)
+	}
+	var requesters []models.FriendRequestUser
+	testhelpers.DecodeResponse(t, listBytes, &requesters)
+	testhelpers.AssertEqual(t, 1, len(requesters))
+	if len(requesters) == 1 {
+		testhelpers.AssertEqual(t, "大学で同じゼミだった user1 です", requesters[0].Message)
+	}
+
+	// 申請し合った場合は、後からの申請で両方の申請が承認されて友達になる
+	resp2, err := client.Post(fmt.Sprintf("%s/request_friend?id=%d&friend_id=%d", ts.URL, user2ID, user1ID), "", nil)
+	if err != nil {
+		t.Fatalf("failed to execute request: %v", err)
+	}
+	defer resp2.Body.Close()
+	testhelpers.AssertEqual(t, http.StatusOK, resp2.StatusCode)
+
+	bodyBytes2, err := io.ReadAll(resp2.Body)
+	if err != nil {
+		t.Fatalf("failed to read response body: %v", err)
+	}
+	testhelpers.AssertEqual(t, "Friend request accepted", testhelpers.DecodeMessage(t, bodyBytes2))
+
+	var linkCount, pendingCount int
+	err = db.QueryRow("SELECT COUNT(*) FROM friend_link WHERE user1_id IN (?, ?)", user1ID, user2ID).Scan(&linkCount)
+	testhelpers.AssertNoError(t, err)
+	testhelpers.AssertEqual(t, 2, linkCount)
+	err = db.QueryRow("SELECT COUNT(*) FROM friend_requests WHERE requester_id IN (?, ?) AND status = 'pending'", user1ID, user2ID).Scan(&pendingCount)
+	testhelpers.AssertNoError(t, err)
+	testhelpers.AssertEqual(t, 0, pendingCount)
+
+	// 同時に申請し合ってもデッドロックせず、どちらかの申請で友達になる
+	for i := 0; i < 5; i++ {
+		if _, err := db.Exec("DELETE FROM friend_link WHERE user1_id IN (?, ?)", user1ID, user2ID); err != nil {
+			t.Fatalf("failed to delete friend links: %v", err)
+		}
+		if _, err := db.Exec("DELETE FROM friend_requests WHERE requester_id IN (?, ?)", user1ID, user2ID); err != nil {
+			t.Fatalf("failed to delete friend requests: %v", err)
+		}
+
+		var wg sync.WaitGroup
+		errs := make([]error, 2)
+		for j, pair := range [][2]int64{{user1ID, user2ID}, {user2ID, user1ID}} {
+			wg.Add(1)
+			go func(j int, pair [2]int64) {
+				defer wg.Done()
+				_, errs[j] = friendRepo.RequestFriend(pair[0], pair[1], "")
+			}(j, pair)
+		}
+		wg.Wait()
+		for _, err := range errs {
+			testhelpers.AssertNoError(t, err)
+		}
+
+		err = db.QueryRow("SELECT COUNT(*) FROM friend_link WHERE user1_id IN (?, ?)", user1ID, user2ID).Scan(&linkCount)
+		testhelpers.AssertNoError(t, err)
+		testhelpers.AssertEqual(t, 2, linkCount)
+	}
 }
 
 func setupTestDataForRequestFriend(db *sql.DB) (int64, int64, func(), error) {
@@ -76,10 +161,11 @@ func setupTestDataForRequestFriend(db *sql.DB) (int64, int64, func(), error) {
 		return 0, 0, nil, fmt.Errorf("failed to create user2: %v", err)
 	}
 
-	// テストデータの削除用関数
+	// テストデータの削除用関数（DeleteUser は退会にとどまるため、行を直接削除する）
 	cleanupFunc := func() {
-		userRepo.DeleteUser(user1.ID)
-		userRepo.DeleteUser(user2.ID)
+		if _, err := db.Exec("DELETE FROM users WHERE id IN (?, ?)", user1.ID, user2.ID); err != nil {
+			panic(err)
+		}
 	}
 	return user1.ID, user2.ID, cleanupFunc, nil
 }
@@ -0,0 +1,135 @@
+package integration_tests
+
+import (
+	"fmt"
+	"io"
+	"minimal_sns_app/configs"
+	"minimal_sns_app/domain/models"
+	"minimal_sns_app/handlers"
+	"minimal_sns_app/repository"
+	"minimal_sns_app/testhelpers"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"database/sql"
+
+	"github.com/labstack/echo/v4"
+)
+
+// テスト対象 /search_users?id=1&q=ali&limit=10 response: 200 [{"id":2,"name":"alice",...}] or 400 "Invalid query" or 500 "Failed to search users"
+// 全角半角・大文字小文字・ひらがなカタカナを区別せずに検索でき、検索者をブロックしたユーザーが除かれることを確認する
+func TestSearchUsersIntegration(t *testing.T) {
+	// 初期設定
+	e := echo.New()
+	e.HTTPErrorHandler = handlers.HTTPErrorHandler
+	conf := configs.Get()
+	db, err := sql.Open(conf.DB.Driver, conf.DB.DataSource)
+	if err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	// リポジトリとハンドラーの設定
+	userRepo := repository.NewUserRepository(db)
+	userHandler := handlers.NewUserHandler(userRepo, conf.User)
+	userHandler.RegisterRoutes(e)
+
+	// テストサーバーの設定
+	ts := httptest.NewServer(e)
+	defer ts.Close()
+
+	ids := make(map[string]int64)
+	for _, name := range []string{"searcher", "Alice", "alicia", "malice", "アリス", "ありさ", "a_b", "axb"} {
+		user, err := userRepo.CreateUser(name)
+		if err != nil {
+			t.Fatalf("failed to create user %s: %v", name, err)
+		}
+		ids[name] = user.ID
+	}
+	defer func() {
+		// テーブルのデータを全削除する（ブロックは users の削除で連鎖して消える）
+		if _, err := db.Exec("DELETE FROM users"); err != nil {
+			panic(err)
+		}
+	}()
+
+	// alicia は searcher をブロックしている
+	if _, err := db.Exec("INSERT INTO block_list (user1_id, user2_id) VALUES (?, ?)", ids["alicia"], ids["searcher"]); err != nil {
+		t.Fatalf("failed to insert block: %v", err)
+	}
+
+	client := &http.Client{}
+	search := func(params url.Values) (int, []models.User, *handlers.Meta) {
+		params.Set("id", fmt.Sprint(ids["searcher"]))
+		resp, err := client.Get(fmt.Sprintf("%s/search_users?%s", ts.URL, params.Encode()))
+		if err != nil {
+			t.Fatalf("failed to execute request: %v", err)
+		}
+		defer resp.Body.Close()
+		bodyBytes, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("failed to read response body: %v", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return resp.StatusCode, nil, nil
+		}
+		var users []models.User
+		envelope := testhelpers.DecodeResponse(t, bodyBytes, &users)
+		return resp.StatusCode, users, envelope.Meta
+	}
+	names := func(users []models.User) []string {
+		got := []string{}
+		for _, user := range users {
+			got = append(got, user.Name)
+		}
+		return got
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		match    string
+		expected []string
+	}{
+		{"前方一致", "ali", "", []string{"Alice"}},
+		{"全角と大文字", "ＡＬＩ", "", []string{"Alice"}},
+		{"ひらがなでカタカナを検索", "ありす", "", []string{"アリス"}},
+		{"半角カタカナ", "ｱﾘ", "", []string{"アリス", "ありさ"}},
+		{"部分一致", "lic", models.UserSearchSubstring, []string{"Alice", "malice"}},
+		{"ワイルドカードはそのまま検索される", "a_", "", []string{"a_b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := url.Values{"q": {tt.query}, "limit": {"10"}}
+			if tt.match != "" {
+				params.Set("match", tt.match)
+			}
+			status, users, _ := search(params)
+			testhelpers.AssertEqual(t, http.StatusOK, status)
+			testhelpers.AssertDeepEqual(t, tt.expected, names(users))
+		})
+	}
+
+	// カーソルで次のページを取得できる
+	status, users, meta := search(url.Values{"q": {"あり"}, "limit": {"1"}})
+	testhelpers.AssertEqual(t, http.StatusOK, status)
+	testhelpers.AssertDeepEqual(t, []string{"アリス"}, names(users))
+	if meta != nil && meta.NextCursor != "" {
+		status, users, _ = search(url.Values{"q": {"あり"}, "limit": {"1"}, "cursor": {meta.NextCursor}})
+		testhelpers.AssertEqual(t, http.StatusOK, status)
+		testhelpers.AssertDeepEqual(t, []string{"ありさ"}, names(users))
+	} else {
+		t.Errorf("expected a next cursor")
+	}
+
+	// 不正な検索語、一致方法は 400 になる
+	for _, params := range []url.Values{
+		{"q": {" "}, "limit": {"10"}},
+		{"q": {"ali"}, "limit": {"10"}, "match": {"suffix"}},
+	} {
+		status, _, _ := search(params)
+		testhelpers.AssertEqual(t, http.StatusBadRequest, status)
+	}
+}
@@ -0,0 +1,161 @@
+package integration_tests
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"minimal_sns_app/configs"
+	"minimal_sns_app/domain/models"
+	"minimal_sns_app/handlers"
+	"minimal_sns_app/repository"
+	"minimal_sns_app/testhelpers"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"database/sql"
+
+	"github.com/labstack/echo/v4"
+)
+
+// テスト対象 /friend?id=1&friend_id=2 body: {"close_friend":true,"nickname":"ali"} response: 200 "Friend updated" or 400 "Invalid nickname" or 400 "Nothing to update" or 404 "Not found" or 500 "Failed to update friend"
+// 友達の設定が自分の側だけに反映されること、友達になった日時で並べ替えられることをあわせて確認する
+func TestUpdateFriendIntegration(t *testing.T) {
+	// 初期設定
+	e := echo.New()
+	e.HTTPErrorHandler = handlers.HTTPErrorHandler
+	conf := configs.Get()
+	db, err := sql.Open(conf.DB.Driver, conf.DB.DataSource)
+	if err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	// リポジトリとハンドラーの設定
+	friendRepo := repository.NewFriendRepository(db)
+	friendHandler := handlers.NewFriendHandler(friendRepo)
+	friendHandler.RegisterRoutes(e)
+
+	// テストサーバーの設定
+	ts := httptest.NewServer(e)
+	defer ts.Close()
+
+	userRepo := repository.NewUserRepository(db)
+	var users []*models.User
+	for _, name := range []string{"alice", "bob", "charlie", "dave"} {
+		user, err := userRepo.CreateUser(name)
+		if err != nil {
+			t.Fatalf("failed to create user %s: %v", name, err)
+		}
+		users = append(users, user)
+	}
+	alice, bob, charlie, dave := users[0], users[1], users[2], users[3]
+	defer func() {
+		// テーブルのデータを全削除する（友達関係は users の削除で連鎖して消える）
+		if _, err := db.Exec("DELETE FROM users"); err != nil {
+			panic(err)
+		}
+	}()
+
+	// alice は bob と1日前に、charlie と今日友達になった
+	query := `INSERT INTO friend_link (user1_id, user2_id, created_at) VALUES (?, ?, NOW() - INTERVAL 1 DAY), (?, ?, NOW() - INTERVAL 1 DAY), (?, ?, NOW()), (?, ?, NOW())`
+	if _, err := db.Exec(query, alice.ID, bob.ID, bob.ID, alice.ID, alice.ID, charlie.ID, charlie.ID, alice.ID); err != nil {
+		t.Fatalf("failed to insert friend links: %v", err)
+	}
+
+	client := &http.Client{}
+	patch := func(userID int64, friendID int64, body string) int {
+		req, err := http.NewRequest(http.MethodPatch, fmt.Sprintf("%s/friend?id=%d&friend_id=%d", ts.URL, userID, friendID), bytes.NewBufferString(body))
+		if err != nil {
+			t.Fatalf("failed to create request: %v", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("failed to execute request: %v", err)
+		}
+		defer resp.Body.Close()
+		return resp.StatusCode
+	}
+	getFriends := func(userID int64, sort string) []models.Friend {
+		resp, err := client.Get(fmt.Sprintf("%s/get_friend_list?id=%d&sort=%s", ts.URL, userID, sort))
+		if err != nil {
+			t.Fatalf("failed to execute request: %v", err)
+		}
+		defer resp.Body.Close()
+		testhelpers.AssertEqual(t, http.StatusOK, resp.StatusCode)
+		bodyBytes, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("failed to read response body: %v", err)
+		}
+		var friends []models.Friend
+		testhelpers.DecodeResponse(t, bodyBytes, &friends)
+		return friends
+	}
+
+	// alice が bob を親しい友達にしてニックネームを付ける
+	testhelpers.AssertEqual(t, http.StatusOK, patch(alice.ID, bob.ID, `{"close_friend":true,"nickname":"  bobby  "}`))
+
+	// alice から見た bob に設定が反映され、ニックネームは前後の空白が除かれる
+	friends := getFriends(alice.ID, "")
+	testhelpers.AssertEqual(t, 2, len(friends))
+	if len(friends) == 2 {
+		testhelpers.AssertEqual(t, bob.ID, friends[0].ID)
+		testhelpers.AssertEqual(t, true, friends[0].CloseFriend)
+		testhelpers.AssertEqual(t, "bobby", friends[0].Nickname)
+		testhelpers.AssertEqual(t, true, friends[0].FriendedAt != nil)
+		testhelpers.AssertEqual(t, false, friends[1].CloseFriend)
+	}
+
+	// bob から見た alice は変わらない
+	friends = getFriends(bob.ID, "")
+	testhelpers.AssertEqual(t, 1, len(friends))
+	if len(friends) == 1 {
+		testhelpers.AssertEqual(t, false, friends[0].CloseFriend)
+		testhelpers.AssertEqual(t, "", friends[0].Nickname)
+	}
+
+	// 友達になった日時の新しい順では charlie が先になる
+	friends = getFriends(alice.ID, models.FriendSortFriendedAt)
+	testhelpers.AssertEqual(t, 2, len(friends))
+	if len(friends) == 2 {
+		testhelpers.AssertEqual(t, charlie.ID, friends[0].ID)
+		testhelpers.AssertEqual(t, bob.ID, friends[1].ID)
+	}
+
+	// 空のニックネームでニックネームが消え、指定しなかった項目は変わらない
+	testhelpers.AssertEqual(t, http.StatusOK, patch(alice.ID, bob.ID, `{"nickname":""}`))
+	friends = getFriends(alice.ID, "")
+	if len(friends) == 2 {
+		testhelpers.AssertEqual(t, true, friends[0].CloseFriend)
+		testhelpers.AssertEqual(t, "", friends[0].Nickname)
+	}
+
+	// 同じ値での更新も成功する
+	testhelpers.AssertEqual(t, http.StatusOK, patch(alice.ID, bob.ID, `{"close_friend":true}`))
+
+	tests := []struct {
+		name     string
+		friendID int64
+		body     string
+		expected int
+	}{
+		{"友達でない相手", dave.ID, `{"close_friend":true}`, http.StatusNotFound},
+		{"更新する項目がない", bob.ID, `{}`, http.StatusBadRequest},
+		{"長すぎるニックネーム", bob.ID, fmt.Sprintf(`{"nickname":"%s"}`, bytes.Repeat([]byte("a"), 65)), http.StatusBadRequest},
+		{"制御文字を含むニックネーム", bob.ID, `{"nickname":"a\u0007b"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testhelpers.AssertEqual(t, tt.expected, patch(alice.ID, tt.friendID, tt.body))
+		})
+	}
+
+	// 不正な並び順は 400 になる
+	resp, err := client.Get(fmt.Sprintf("%s/get_friend_list?id=%d&sort=unknown", ts.URL, alice.ID))
+	if err != nil {
+		t.Fatalf("failed to execute request: %v", err)
+	}
+	resp.Body.Close()
+	testhelpers.AssertEqual(t, http.StatusBadRequest, resp.StatusCode)
+}
@@ -0,0 +1,127 @@
+package integration_tests
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"minimal_sns_app/configs"
+	"minimal_sns_app/domain/models"
+	"minimal_sns_app/handlers"
+	"minimal_sns_app/repository"
+	"minimal_sns_app/testhelpers"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"database/sql"
+
+	"github.com/labstack/echo/v4"
+)
+
+// テスト対象 /user?id=1 body: {"display_name":"Alice","bio":"hello"} response: 200 {"id":1,"name":"alice","display_name":"Alice",...} or 400 "Invalid ..." or 400 "Nothing to update" or 404 "Not found" or 500 "Failed to update user"
+// 指定した項目だけが更新され、項目ごとに検証されることを確認する
+func TestUpdateUserIntegration(t *testing.T) {
+	// 初期設定
+	e := echo.New()
+	e.HTTPErrorHandler = handlers.HTTPErrorHandler
+	conf := configs.Get()
+	db, err := sql.Open(conf.DB.Driver, conf.DB.DataSource)
+	if err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	// リポジトリとハンドラーの設定
+	userRepo := repository.NewUserRepository(db)
+	userHandler := handlers.NewUserHandler(userRepo, conf.User)
+	userHandler.RegisterRoutes(e)
+
+	// テストサーバーの設定
+	ts := httptest.NewServer(e)
+	defer ts.Close()
+
+	alice, err := userRepo.CreateUser("alice")
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	defer func() {
+		// テーブルのデータを全削除する
+		if _, err := db.Exec("DELETE FROM users"); err != nil {
+			panic(err)
+		}
+	}()
+
+	client := &http.Client{}
+	patch := func(userID int64, body string) (int, []byte) {
+		req, err := http.NewRequest(http.MethodPatch, fmt.Sprintf("%s/user?id=%d", ts.URL, userID), bytes.NewBufferString(body))
+		if err != nil {
+			t.Fatalf("failed to create request: %v", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("failed to execute request: %v", err)
+		}
+		defer resp.Body.Close()
+		bodyBytes, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("failed to read response body: %v", err)
+		}
+		return resp.StatusCode, bodyBytes
+	}
+
+	// プロフィールをまとめて設定する
+	status, bodyBytes := patch(alice.ID, `{"display_name":" Alice ","bio":"hello\nworld","birthday":"2000-01-31","location":"Tokyo","avatar_path":"avatars/1.png"}`)
+	testhelpers.AssertEqual(t, http.StatusOK, status)
+	var user models.User
+	testhelpers.DecodeResponse(t, bodyBytes, &user)
+	testhelpers.AssertEqual(t, "alice", user.Name)
+	testhelpers.AssertEqual(t, "Alice", user.DisplayName)
+	testhelpers.AssertEqual(t, "hello\nworld", user.Bio)
+	testhelpers.AssertEqual(t, "2000-01-31", user.Birthday)
+	testhelpers.AssertEqual(t, "Tokyo", user.Location)
+	testhelpers.AssertEqual(t, "avatars/1.png", user.AvatarPath)
+
+	// 一部の項目だけを更新すると、他の項目はそのまま残る。空の誕生日は誕生日を消す
+	status, bodyBytes = patch(alice.ID, `{"location":"Osaka","birthday":""}`)
+	testhelpers.AssertEqual(t, http.StatusOK, status)
+	user = models.User{}
+	testhelpers.DecodeResponse(t, bodyBytes, &user)
+	testhelpers.AssertEqual(t, "Osaka", user.Location)
+	testhelpers.AssertEqual(t, "", user.Birthday)
+	testhelpers.AssertEqual(t, "Alice", user.DisplayName)
+	testhelpers.AssertEqual(t, "avatars/1.png", user.AvatarPath)
+
+	tests := []struct {
+		name     string
+		userID   int64
+		body     string
+		expected int
+	}{
+		{"存在しないユーザー", alice.ID + 1000, `{"bio":"hi"}`, http.StatusNotFound},
+		{"更新する項目がない", alice.ID, `{}`, http.StatusBadRequest},
+		{"不正な JSON", alice.ID, `{"bio":`, http.StatusBadRequest},
+		{"長すぎる表示名", alice.ID, fmt.Sprintf(`{"display_name":"%s"}`, strings.Repeat("あ", 65)), http.StatusBadRequest},
+		{"改行を含む表示名", alice.ID, `{"display_name":"a\nb"}`, http.StatusBadRequest},
+		{"長すぎる自己紹介", alice.ID, fmt.Sprintf(`{"bio":"%s"}`, strings.Repeat("a", 501)), http.StatusBadRequest},
+		{"存在しない日付", alice.ID, `{"birthday":"2001-02-29"}`, http.StatusBadRequest},
+		{"未来の誕生日", alice.ID, `{"birthday":"2999-01-01"}`, http.StatusBadRequest},
+		{"親ディレクトリを指すパス", alice.ID, `{"avatar_path":"../secret.png"}`, http.StatusBadRequest},
+		{"URL のアバター画像", alice.ID, `{"avatar_path":"https://example.com/a.png"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, _ := patch(tt.userID, tt.body)
+			testhelpers.AssertEqual(t, tt.expected, status)
+		})
+	}
+
+	// 検証に失敗した更新は反映されない
+	updated, err := userRepo.GetUser(alice.ID)
+	if err != nil {
+		t.Fatalf("failed to get user: %v", err)
+	}
+	testhelpers.AssertEqual(t, "Alice", updated.DisplayName)
+	testhelpers.AssertEqual(t, "hello\nworld", updated.Bio)
+}
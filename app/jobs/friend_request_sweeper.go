@@ -0,0 +1,39 @@
+// jobs/friend_request_sweeper.go
+package jobs
+
+import (
+	"fmt"
+	"minimal_sns_app/configs"
+	"minimal_sns_app/logutils"
+	"minimal_sns_app/repository"
+)
+
+// FriendRequestSweeper periodically expires the pending friend requests older than the configured TTL.
+type FriendRequestSweeper struct {
+	FriendRepo repository.FriendRepository
+	Config     configs.FriendRequestConfig
+
+	*periodicJob
+}
+
+// NewFriendRequestSweeper creates a new FriendRequestSweeper sweeping every SweepInterval. Call Start to run it.
+// The sweeper is disabled when TTL, SweepInterval or SweepBatchSize is not positive: a TTL that is not
+// positive would expire every pending request at once.
+func NewFriendRequestSweeper(friendRepo repository.FriendRepository, conf configs.FriendRequestConfig) *FriendRequestSweeper {
+	s := &FriendRequestSweeper{FriendRepo: friendRepo, Config: conf}
+	enabled := conf.TTL > 0 && conf.SweepBatchSize > 0
+	s.periodicJob = newPeriodicJob("Friend request sweeper", conf.SweepInterval, enabled, s.sweep)
+	return s
+}
+
+// sweep expires the stale friend requests. Errors are logged and retried on the next tick.
+func (s *FriendRequestSweeper) sweep() {
+	expired, err := s.FriendRepo.ExpireFriendRequests(s.Config.TTL, s.Config.SweepBatchSize)
+	if err != nil {
+		logutils.Error(fmt.Sprintf("Failed to expire friend requests: %v", err))
+		return
+	}
+	if expired > 0 {
+		logutils.Info(fmt.Sprintf("Expired %d friend requests", expired))
+	}
+}
@@ -0,0 +1,66 @@
+// jobs/periodic_job.go
+package jobs
+
+import (
+	"minimal_sns_app/logutils"
+	"sync"
+	"time"
+)
+
+// periodicJob runs a task once right away and then every interval, until it is stopped.
+// The jobs embed it and only provide the task.
+type periodicJob struct {
+	name     string
+	interval time.Duration
+	enabled  bool
+	task     func()
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// newPeriodicJob creates a periodicJob running task every interval. A disabled job does nothing when started.
+func newPeriodicJob(name string, interval time.Duration, enabled bool, task func()) *periodicJob {
+	return &periodicJob{
+		name:     name,
+		interval: interval,
+		enabled:  enabled && interval > 0,
+		task:     task,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start runs the job in a goroutine, running the task once right away and then every interval.
+func (j *periodicJob) Start() {
+	if !j.enabled {
+		logutils.Warning(j.name + " is disabled")
+		close(j.done)
+		return
+	}
+
+	go func() {
+		defer close(j.done)
+
+		ticker := time.NewTicker(j.interval)
+		defer ticker.Stop()
+
+		for {
+			j.task()
+			select {
+			case <-ticker.C:
+			case <-j.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop asks the job to stop and waits for the task in progress, if any, to finish.
+func (j *periodicJob) Stop() {
+	j.stopOnce.Do(func() {
+		close(j.stop)
+	})
+	<-j.done
+}
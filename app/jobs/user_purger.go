@@ -0,0 +1,39 @@
+// jobs/user_purger.go
+package jobs
+
+import (
+	"fmt"
+	"minimal_sns_app/configs"
+	"minimal_sns_app/logutils"
+	"minimal_sns_app/repository"
+)
+
+// UserPurger periodically deletes for good the users deactivated longer than the configured grace period.
+type UserPurger struct {
+	UserRepo repository.UserRepository
+	Config   configs.UserConfig
+
+	*periodicJob
+}
+
+// NewUserPurger creates a new UserPurger purging every PurgeInterval. Call Start to run it.
+// The purger is disabled when DeactivationGracePeriod, PurgeInterval or PurgeBatchSize is not positive:
+// a grace period that is not positive would delete every deactivated user at once.
+func NewUserPurger(userRepo repository.UserRepository, conf configs.UserConfig) *UserPurger {
+	p := &UserPurger{UserRepo: userRepo, Config: conf}
+	enabled := conf.DeactivationGracePeriod > 0 && conf.PurgeBatchSize > 0
+	p.periodicJob = newPeriodicJob("User purger", conf.PurgeInterval, enabled, p.purge)
+	return p
+}
+
+// purge deletes the users whose grace period is over. Errors are logged and retried on the next tick.
+func (p *UserPurger) purge() {
+	purged, err := p.UserRepo.PurgeDeactivatedUsers(p.Config.DeactivationGracePeriod, p.Config.PurgeBatchSize)
+	if err != nil {
+		logutils.Error(fmt.Sprintf("Failed to purge deactivated users: %v", err))
+		return
+	}
+	if purged > 0 {
+		logutils.Info(fmt.Sprintf("Purged %d deactivated users", purged))
+	}
+}
@@ -1,9 +1,12 @@
 package logutils
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"log"
 	"os"
+	"runtime/debug"
 
 	"github.com/labstack/echo/v4"
 )
@@ -46,8 +49,44 @@ func RequestLoggerMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		req := c.Request()
 		logmsg := fmt.Sprintf(
-			"Recived request\nmethod: %s\nuri: %s\n headers: %s\nremote_addr%s", req.Method, req.RequestURI, req.Header, req.RemoteAddr)
+			"Recived request\nrequest_id: %s\nmethod: %s\nuri: %s\n headers: %s\nremote_addr%s",
+			c.Response().Header().Get(echo.HeaderXRequestID), req.Method, req.RequestURI, req.Header, req.RemoteAddr)
 		Info(logmsg)
 		return next(c)
 	}
 }
+
+// RequestIDMiddleware makes sure every request has an ID, reusing the X-Request-ID header sent by the proxy
+// when present, and echoes it back in the response.
+func RequestIDMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		requestID := c.Request().Header.Get(echo.HeaderXRequestID)
+		if requestID == "" {
+			requestID = newRequestID()
+		}
+		c.Response().Header().Set(echo.HeaderXRequestID, requestID)
+		return next(c)
+	}
+}
+
+// newRequestID generates a random request ID.
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		Error(err.Error())
+	}
+	return hex.EncodeToString(b)
+}
+
+// RecoverMiddleware turns a panic in a handler into an error, so that it goes through the HTTP error handler.
+func RecoverMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				Error(fmt.Sprintf("panic recovered: %v\n%s", r, debug.Stack()))
+				err = fmt.Errorf("panic: %v", r)
+			}
+		}()
+		return next(c)
+	}
+}
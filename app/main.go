@@ -1,12 +1,20 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"minimal_sns_app/configs"
 	"minimal_sns_app/handlers"
+	"minimal_sns_app/jobs"
 	"minimal_sns_app/logutils"
 	"minimal_sns_app/repository"
+	"net/http"
+	"os"
+	"os/signal"
 	"strconv"
+	"syscall"
+	"time"
 
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/labstack/echo/v4"
@@ -23,19 +31,67 @@ func main() {
 	}
 	defer db.Close()
 
+	if len(os.Args) > 1 && os.Args[1] == checkFriendshipsCommand {
+		if err := runCheckFriendships(db, os.Args[2:]); err != nil {
+			logutils.Error(err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
 	e := echo.New()
+	e.HTTPErrorHandler = handlers.HTTPErrorHandler
 	e.GET("/", func(c echo.Context) error {
 		return c.String(200, "I'm alive!")
 	})
+	e.Use(logutils.RequestIDMiddleware)
 	e.Use(logutils.RequestLoggerMiddleware)
+	e.Use(logutils.RecoverMiddleware)
 
 	friendRepo := repository.NewFriendRepository(db)
 	friendHandler := handlers.NewFriendHandler(friendRepo)
 	friendHandler.RegisterRoutes(e)
 
+	friendListRepo := repository.NewFriendListRepository(db)
+	friendListHandler := handlers.NewFriendListHandler(friendListRepo)
+	friendListHandler.RegisterRoutes(e)
+
+	followRepo := repository.NewFollowRepository(db)
+	followHandler := handlers.NewFollowHandler(followRepo)
+	followHandler.RegisterRoutes(e)
+
 	userRepo := repository.NewUserRepository(db)
-	userHandler := handlers.NewUserHandler(userRepo)
+	userHandler := handlers.NewUserHandler(userRepo, conf.User)
 	userHandler.RegisterRoutes(e)
 
-	e.Logger.Fatal(e.Start(":" + strconv.Itoa(conf.Server.Port)))
+	invariantRepo := repository.NewInvariantRepository(db)
+	adminHandler := handlers.NewAdminHandler(invariantRepo)
+	adminHandler.RegisterRoutes(e)
+
+	// Expire stale friend requests in the background
+	sweeper := jobs.NewFriendRequestSweeper(friendRepo, conf.FriendRequest)
+	sweeper.Start()
+
+	// Delete the users whose deactivation grace period is over in the background
+	purger := jobs.NewUserPurger(userRepo, conf.User)
+	purger.Start()
+
+	go func() {
+		if err := e.Start(":" + strconv.Itoa(conf.Server.Port)); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			e.Logger.Fatal(err)
+		}
+	}()
+
+	// Wait for an interrupt, then let the requests in flight and the background jobs finish before exiting
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	<-quit
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := e.Shutdown(ctx); err != nil {
+		e.Logger.Error(err)
+	}
+	sweeper.Stop()
+	purger.Stop()
 }
@@ -0,0 +1,55 @@
+// repository/errors.go
+
+package repository
+
+import (
+	"errors"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Errors returned by the repositories. Callers should compare with errors.Is.
+var (
+	// ErrNotFound is returned when a user or friend request does not exist.
+	ErrNotFound = errors.New("not found")
+	// ErrAlreadyFriends is returned when the users are already friends.
+	ErrAlreadyFriends = errors.New("already friends")
+	// ErrDuplicate is returned when the row to create already exists, such as a second pending friend request.
+	ErrDuplicate = errors.New("duplicate request")
+	// ErrBlocked is returned when one of the users has blocked the other.
+	ErrBlocked = errors.New("users have blocked each other")
+	// ErrSelfReference is returned when a user targets themself.
+	ErrSelfReference = errors.New("user cannot target themself")
+	// ErrNotPending is returned when a friend request has already been answered.
+	ErrNotPending = errors.New("friend request is not pending")
+	// ErrNotFriends is returned when an operation needs the users to be friends and they are not.
+	ErrNotFriends = errors.New("users are not friends")
+	// ErrCooldown is returned when a friend request is sent again too soon after being declined.
+	ErrCooldown = errors.New("friend request was declined recently")
+	// ErrInvalidBatchSize is returned when a batched operation is given a batch size that is not positive.
+	ErrInvalidBatchSize = errors.New("batch size must be positive")
+)
+
+// MySQL error numbers translated by translateError.
+const (
+	mysqlErrDuplicateEntry       = 1062
+	mysqlErrNoReferencedRow      = 1452
+	mysqlErrCheckConstraintFails = 3819
+)
+
+// translateError maps MySQL constraint violations to the repository errors and returns other errors unchanged.
+func translateError(err error) error {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return err
+	}
+	switch mysqlErr.Number {
+	case mysqlErrDuplicateEntry:
+		return ErrDuplicate
+	case mysqlErrNoReferencedRow:
+		return ErrNotFound
+	case mysqlErrCheckConstraintFails:
+		return ErrSelfReference
+	}
+	return err
+}
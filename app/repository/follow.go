@@ -0,0 +1,145 @@
+// repository/follow.go
+
+package repository
+
+import (
+	"database/sql"
+	"minimal_sns_app/domain/models"
+	"minimal_sns_app/logutils"
+)
+
+// FollowRepository defines the interface for follow data access.
+// Unlike a friendship, a follow goes one way and needs no approval.
+type FollowRepository interface {
+	Follow(userID int64, targetID int64) error
+	Unfollow(userID int64, targetID int64) error
+	GetFollowersCursor(userID int64, afterID int64, limit int) ([]models.Friend, error)
+	GetFollowingCursor(userID int64, afterID int64, limit int) ([]models.Friend, error)
+	GetFollowCounts(userID int64) (*models.FollowCounts, error)
+}
+
+type followRepository struct {
+	db *sql.DB
+}
+
+// NewFollowRepository creates a new instance of a FollowRepository.
+func NewFollowRepository(db *sql.DB) FollowRepository {
+	return &followRepository{db: db}
+}
+
+// Follow makes userID follow targetID.
+// It fails with ErrSelfReference, ErrBlocked when either user has blocked the other,
+// ErrDuplicate when userID already follows targetID and ErrNotFound when a user does not exist or is deactivated.
+func (r *followRepository) Follow(userID int64, targetID int64) error {
+	if userID == targetID {
+		logutils.Error("Follow oneself")
+		return ErrSelfReference
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		logutils.Error(err.Error())
+		return err
+	}
+
+	// A deactivated user can neither be followed nor follow anyone
+	active, err := areActiveUsers(tx, userID, targetID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if !active {
+		tx.Rollback()
+		logutils.Error("User not found")
+		return ErrNotFound
+	}
+
+	blocked, err := isBlocked(tx, userID, targetID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if blocked {
+		tx.Rollback()
+		logutils.Error("Users have blocked each other")
+		return ErrBlocked
+	}
+
+	query := `INSERT INTO follow (follower_id, followee_id) VALUES (?, ?)`
+	if _, err := tx.Exec(query, userID, targetID); err != nil {
+		tx.Rollback()
+		logutils.Error(err.Error())
+		return translateError(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		logutils.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+// Unfollow makes userID stop following targetID.
+// It fails with ErrNotFound when userID did not follow targetID.
+func (r *followRepository) Unfollow(userID int64, targetID int64) error {
+	query := `DELETE FROM follow WHERE follower_id = ? AND followee_id = ?`
+	result, err := r.db.Exec(query, userID, targetID)
+	if err != nil {
+		logutils.Error(err.Error())
+		return err
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		logutils.Error(err.Error())
+		return err
+	}
+	if deleted == 0 {
+		logutils.Error("User is not followed")
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetFollowersCursor retrieves up to limit users following the given user ID whose ID is greater than afterID.
+func (r *followRepository) GetFollowersCursor(userID int64, afterID int64, limit int) ([]models.Friend, error) {
+	query := `SELECT u.id, u.name FROM users AS u
+			JOIN follow AS f ON u.id = f.follower_id
+			WHERE f.followee_id = ? AND u.id > ? AND u.deactivated_at IS NULL AND ` + notBlockedCondition("u.id") + `
+			ORDER BY u.id
+			LIMIT ?`
+	return queryFriends(r.db, query, userID, afterID, userID, userID, limit)
+}
+
+// GetFollowingCursor retrieves up to limit users followed by the given user ID whose ID is greater than afterID.
+func (r *followRepository) GetFollowingCursor(userID int64, afterID int64, limit int) ([]models.Friend, error) {
+	query := `SELECT u.id, u.name FROM users AS u
+			JOIN follow AS f ON u.id = f.followee_id
+			WHERE f.follower_id = ? AND u.id > ? AND u.deactivated_at IS NULL AND ` + notBlockedCondition("u.id") + `
+			ORDER BY u.id
+			LIMIT ?`
+	return queryFriends(r.db, query, userID, afterID, userID, userID, limit)
+}
+
+// GetFollowCounts retrieves the number of followers and followed users of the given user ID.
+// Blocks remove follows in both directions, so the counts need no block filtering, but deactivated users are not counted.
+func (r *followRepository) GetFollowCounts(userID int64) (*models.FollowCounts, error) {
+	var counts models.FollowCounts
+	query := `SELECT
+			(SELECT COUNT(*) FROM follow WHERE followee_id = ? AND ` + activeUserCondition("follower_id") + `),
+			(SELECT COUNT(*) FROM follow WHERE follower_id = ? AND ` + activeUserCondition("followee_id") + `)`
+	if err := r.db.QueryRow(query, userID, userID).Scan(&counts.Followers, &counts.Following); err != nil {
+		logutils.Error(err.Error())
+		return nil, err
+	}
+	return &counts, nil
+}
+
+// deleteFollows removes the follows between two users in both directions.
+func deleteFollows(tx *sql.Tx, userID int64, otherID int64) error {
+	query := `DELETE FROM follow WHERE (follower_id = ? AND followee_id = ?) OR (follower_id = ? AND followee_id = ?)`
+	if _, err := tx.Exec(query, userID, otherID, otherID, userID); err != nil {
+		logutils.Error(err.Error())
+		return err
+	}
+	return nil
+}
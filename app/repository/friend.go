@@ -5,26 +5,43 @@ package repository
 import (
 	"database/sql"
 	"errors"
-	"fmt"
 	"minimal_sns_app/domain/models"
 	"minimal_sns_app/logutils"
+	"strings"
+	"time"
 )
 
 // FriendRepository defines the interface for friend data access.
 type FriendRepository interface {
-	RequestFriend(userID int64, friendID int64) error
-	GetFriendRequesterList(userID int64) ([]models.Friend, error)
-	GetFriendRequestedList(userID int64) ([]models.Friend, error)
+	RequestFriend(userID int64, friendID int64, message string) (bool, error)
+	GetFriendRequesterList(userID int64, status string) ([]models.FriendRequestUser, error)
+	GetFriendRequestedList(userID int64, status string) ([]models.FriendRequestUser, error)
+	GetFriendRequesterListCursor(userID int64, afterID int64, limit int) ([]models.Friend, error)
+	GetFriendRequestedListCursor(userID int64, afterID int64, limit int) ([]models.Friend, error)
+	GetFriendRequestHistory(userID int64, otherID int64) ([]models.FriendRequest, error)
 	AcceptFriend(userID int64, friendID int64) error
 	DeclineFriend(userID int64, friendID int64) error
-	GetFriends(userID int64) ([]models.Friend, error)
-	GetFriendsPaging(userID int64, limit int, page int) ([]models.Friend, error)
+	CancelFriendRequest(userID int64, friendID int64) error
+	GetFriends(userID int64, sort string) ([]models.Friend, error)
+	GetFriendsInList(userID int64, listID int64, sort string) ([]models.Friend, error)
+	GetFriendsPaging(userID int64, sort string, limit int, offset int) ([]models.Friend, int, error)
+	UpdateFriendLink(userID int64, friendID int64, update models.FriendLinkUpdate) error
+	GetFriendsCursor(userID int64, afterID int64, limit int) ([]models.Friend, error)
 	GetFriendOfFriendList(userID int64) ([]models.Friend, error)
-	GetFriendOfFriendListPaging(userID int64, limit int, page int) ([]models.Friend, error)
+	GetFriendOfFriendListPaging(userID int64, limit int, offset int) ([]models.Friend, int, error)
+	GetFriendOfFriendListCursor(userID int64, afterID int64, limit int) ([]models.Friend, error)
+	GetMutualFriends(userID int64, otherID int64) ([]models.Friend, error)
+	GetFriendRecommendations(userID int64, limit int, offset int) ([]models.Friend, int, error)
+	GetFriendPath(userID int64, targetID int64, maxDepth int) ([]models.Friend, error)
+	GetFriendNetwork(userID int64, depth int) ([]models.Friend, error)
+	GetFriendNetworkPaging(userID int64, depth int, limit int, offset int) ([]models.Friend, int, error)
+	GetFriendNetworkCursor(userID int64, depth int, afterID int64, limit int) ([]models.Friend, error)
 	DeleteFriend(userID int64, friendID int64) error
 	AddBlock(userID int64, blockID int64) error
 	GetBlockList(userID int64) ([]models.Friend, error)
+	GetBlockListCursor(userID int64, afterID int64, limit int) ([]models.Friend, error)
 	DeleteBlock(userID int64, blockID int64) error
+	ExpireFriendRequests(ttl time.Duration, batchSize int) (int, error)
 }
 
 type friendRepository struct {
@@ -36,103 +53,158 @@ func NewFriendRepository(db *sql.DB) FriendRepository {
 	return &friendRepository{db: db}
 }
 
-// RequestFriend creates a friend request from one user to another.
-func (r *friendRepository) RequestFriend(userID int64, friendID int64) error {
-	query := `INSERT INTO friend_requests (requester_id, requested_id) VALUES (?, ?)`
-	_, err := r.db.Exec(query, userID, friendID)
+// RequestFriend creates a friend request from one user to another, with an optional message for the other user.
+// When the other user already has a pending request to the user, both requests are accepted and the users
+// become friends right away, which is reported by returning true.
+// It fails with ErrSelfReference, ErrBlocked when either user has blocked the other, ErrAlreadyFriends,
+// ErrDuplicate when a pending request already exists, ErrCooldown when the last request was declined
+// less than friendRequestCooldown ago and ErrNotFound when a user does not exist or is deactivated.
+func (r *friendRepository) RequestFriend(userID int64, friendID int64, message string) (bool, error) {
+	if userID == friendID {
+		logutils.Error("Friend request to oneself")
+		return false, ErrSelfReference
+	}
+
+	tx, err := r.db.Begin()
 	if err != nil {
 		logutils.Error(err.Error())
-		return err
+		return false, err
 	}
-	return nil
-}
 
-// GetFriendRequesterList retrieves a list of users who have sent a friend request to the given user ID.
-func (r *friendRepository) GetFriendRequesterList(userID int64) ([]models.Friend, error) {
-	var requesters []models.Friend
-	query := `SELECT u.id, u.name FROM users AS u
-			JOIN friend_requests AS fr ON u.id = fr.requester_id
-			WHERE fr.requested_id = ?`
+	// Serialize the requests between the two users, so that crossed requests sent at the same time
+	// are handled one after the other instead of deadlocking on the friend_requests gap locks
+	if err := lockUserPair(tx, userID, friendID); err != nil {
+		tx.Rollback()
+		return false, err
+	}
 
-	rows, err := r.db.Query(query, userID)
+	// Check that neither user has been deactivated: a deactivated user is hidden and cannot send requests either
+	active, err := areActiveUsers(tx, userID, friendID)
 	if err != nil {
-		logutils.Error(err.Error())
-		return nil, err
+		tx.Rollback()
+		return false, err
+	}
+	if !active {
+		tx.Rollback()
+		logutils.Error("User not found")
+		return false, ErrNotFound
 	}
-	defer rows.Close()
 
-	for rows.Next() {
-		var requester models.Friend
-		if err := rows.Scan(&requester.ID, &requester.Name); err != nil {
-			logutils.Error(err.Error())
-			return nil, err
-		}
-		requesters = append(requesters, requester)
+	// Check if either user has blocked the other
+	blocked, err := isBlocked(tx, userID, friendID)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	if blocked {
+		tx.Rollback()
+		logutils.Error("Users have blocked each other")
+		return false, ErrBlocked
 	}
 
-	if err := rows.Err(); err != nil {
+	// Check if the users are already friends
+	var friends bool
+	friendQuery := `SELECT EXISTS (SELECT 1 FROM friend_link WHERE user1_id = ? AND user2_id = ?)`
+	if err := tx.QueryRow(friendQuery, userID, friendID).Scan(&friends); err != nil {
+		tx.Rollback()
 		logutils.Error(err.Error())
-		return nil, err
+		return false, err
+	}
+	if friends {
+		tx.Rollback()
+		logutils.Error("Users are already friends")
+		return false, ErrAlreadyFriends
 	}
 
-	return requesters, nil
-}
-
-// GetFriendRequestedList retrieves a list of users to whom the given user ID has sent a friend request.
-func (r *friendRepository) GetFriendRequestedList(userID int64) ([]models.Friend, error) {
-	var requested []models.Friend
-	query := `SELECT u.id, u.name FROM users AS u
-			JOIN friend_requests AS fr ON u.id = fr.requested_id
-			WHERE fr.requester_id = ?`
-
-	rows, err := r.db.Query(query, userID)
+	// Check if the other user has already requested the user, locking the request against a concurrent answer
+	crossedID, crossed, err := lockCrossedFriendRequest(tx, userID, friendID)
 	if err != nil {
-		logutils.Error(err.Error())
-		return nil, err
+		tx.Rollback()
+		return false, err
 	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var requestee models.Friend
-		if err := rows.Scan(&requestee.ID, &requestee.Name); err != nil {
+	if crossed {
+		if err := acceptCrossedFriendRequest(tx, userID, friendID, message, crossedID); err != nil {
+			tx.Rollback()
+			return false, err
+		}
+		if err := tx.Commit(); err != nil {
 			logutils.Error(err.Error())
-			return nil, err
+			return false, err
 		}
-		requested = append(requested, requestee)
+		return true, nil
 	}
 
-	if err := rows.Err(); err != nil {
+	// Check if the last request was declined too recently
+	cooling, err := inFriendRequestCooldown(tx, userID, friendID)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	if cooling {
+		tx.Rollback()
+		logutils.Error("Friend request was declined recently")
+		return false, ErrCooldown
+	}
+
+	if _, err := insertFriendRequest(tx, userID, friendID, message); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
 		logutils.Error(err.Error())
-		return nil, err
+		return false, err
 	}
+	return false, nil
+}
 
-	return requested, nil
+// GetFriendRequesterList retrieves the users who have sent a friend request with the given status to the given user ID,
+// newest request first.
+func (r *friendRepository) GetFriendRequesterList(userID int64, status string) ([]models.FriendRequestUser, error) {
+	query := `SELECT u.id, u.name, fr.id, fr.status, fr.message, UNIX_TIMESTAMP(fr.created_at) FROM users AS u
+			JOIN friend_requests AS fr ON u.id = fr.requester_id
+			WHERE fr.requested_id = ? AND fr.status = ? AND u.deactivated_at IS NULL AND ` + notBlockedCondition("u.id") + `
+			ORDER BY fr.id DESC`
+	return r.queryFriendRequestUsers(query, userID, status, userID, userID)
+}
+
+// GetFriendRequestedList retrieves the users to whom the given user ID has sent a friend request with the given status,
+// newest request first.
+func (r *friendRepository) GetFriendRequestedList(userID int64, status string) ([]models.FriendRequestUser, error) {
+	query := `SELECT u.id, u.name, fr.id, fr.status, fr.message, UNIX_TIMESTAMP(fr.created_at) FROM users AS u
+			JOIN friend_requests AS fr ON u.id = fr.requested_id
+			WHERE fr.requester_id = ? AND fr.status = ? AND u.deactivated_at IS NULL AND ` + notBlockedCondition("u.id") + `
+			ORDER BY fr.id DESC`
+	return r.queryFriendRequestUsers(query, userID, status, userID, userID)
 }
 
 // AcceptFriend creates a friend link between two users, indicating a successful friend request.
+// It fails with ErrNotFound when there is no request or either user is deactivated, ErrNotPending when the latest one
+// was already answered and ErrAlreadyFriends when the users are already linked.
 func (r *friendRepository) AcceptFriend(userID int64, friendID int64) error {
-	// This should insert into friend_link and delete from friend_requests
-	// Start transaction
 	tx, err := r.db.Begin()
 	if err != nil {
 		logutils.Error(err.Error())
 		return err
 	}
 
-	// Check if friend request exists
-	query := `SELECT status FROM friend_requests WHERE requester_id = ? AND requested_id = ?`
-	var status string
-	if err := tx.QueryRow(query, friendID, userID).Scan(&status); err != nil {
+	// The requester is hidden while deactivated, so their requests cannot be accepted, and a deactivated user cannot accept any
+	active, err := areActiveUsers(tx, userID, friendID)
+	if err != nil {
 		tx.Rollback()
-		logutils.Error(err.Error())
-		return errors.New("friend request does not exist")
+		return err
+	}
+	if !active {
+		tx.Rollback()
+		logutils.Error("User not found")
+		return ErrNotFound
 	}
 
-	// Check if friend request is pending
-	if status != "pending" {
+	// Lock the pending friend request against a concurrent cancel or decline
+	requestID, err := lockPendingFriendRequest(tx, friendID, userID)
+	if err != nil {
 		tx.Rollback()
-		logutils.Error("Friend request is not pending")
-		return errors.New("friend request is not pending")
+		return err
 	}
 
 	// Insert into friend_link
@@ -140,18 +212,17 @@ func (r *friendRepository) AcceptFriend(userID int64, friendID int64) error {
 	if _, err := tx.Exec(insertQuery, userID, friendID, friendID, userID); err != nil {
 		tx.Rollback()
 		logutils.Error(err.Error())
+		if err = translateError(err); errors.Is(err, ErrDuplicate) {
+			return ErrAlreadyFriends
+		}
 		return err
 	}
 
-	// Update friend_requests status to accepted
-	updateQuery := `UPDATE friend_requests SET status = 'accepted' WHERE requester_id = ? AND requested_id = ?`
-	if _, err := tx.Exec(updateQuery, friendID, userID); err != nil {
+	if err := setFriendRequestStatus(tx, requestID, models.FriendRequestAccepted); err != nil {
 		tx.Rollback()
-		logutils.Error(err.Error())
 		return err
 	}
 
-	// Commit transaction
 	if err := tx.Commit(); err != nil {
 		logutils.Error(err.Error())
 		return err
@@ -160,7 +231,8 @@ func (r *friendRepository) AcceptFriend(userID int64, friendID int64) error {
 	return nil
 }
 
-// DeclineFriend removes a friend request.
+// DeclineFriend declines a friend request. The requester can send a new one after friendRequestCooldown.
+// It fails with ErrNotFound when there is no request and ErrNotPending when the latest one was already answered.
 func (r *friendRepository) DeclineFriend(userID int64, friendID int64) error {
 	tx, err := r.db.Begin()
 	if err != nil {
@@ -168,31 +240,45 @@ func (r *friendRepository) DeclineFriend(userID int64, friendID int64) error {
 		return err
 	}
 
-	// Check if friend request exists
-	query := `SELECT status FROM friend_requests WHERE requester_id = ? AND requested_id = ?`
-	var status string
-	if err := tx.QueryRow(query, friendID, userID).Scan(&status); err != nil {
+	requestID, err := lockPendingFriendRequest(tx, friendID, userID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := setFriendRequestStatus(tx, requestID, models.FriendRequestDeclined); err != nil {
 		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
 		logutils.Error(err.Error())
-		return errors.New("friend request does not exist")
+		return err
+	}
+
+	return nil
+}
+
+// CancelFriendRequest withdraws a pending friend request sent by userID to friendID.
+// It fails with ErrNotFound when there is no request and ErrNotPending when the latest one was already answered.
+func (r *friendRepository) CancelFriendRequest(userID int64, friendID int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		logutils.Error(err.Error())
+		return err
 	}
 
-	// Check if friend request is pending
-	if status != "pending" {
+	requestID, err := lockPendingFriendRequest(tx, userID, friendID)
+	if err != nil {
 		tx.Rollback()
-		logutils.Error("Friend request is not pending")
-		return errors.New("friend request is not pending")
+		return err
 	}
 
-	// Update friend_requests status to declined
-	updateQuery := `UPDATE friend_requests SET status = 'declined' WHERE requester_id = ? AND requested_id = ?`
-	if _, err := tx.Exec(updateQuery, friendID, userID); err != nil {
+	if err := setFriendRequestStatus(tx, requestID, models.FriendRequestCanceled); err != nil {
 		tx.Rollback()
-		logutils.Error(err.Error())
 		return err
 	}
 
-	// Commit transaction
 	if err := tx.Commit(); err != nil {
 		logutils.Error(err.Error())
 		return err
@@ -201,48 +287,128 @@ func (r *friendRepository) DeclineFriend(userID int64, friendID int64) error {
 	return nil
 }
 
-// GetFriends retrieves a list of friends for a given user ID.
-func (r *friendRepository) GetFriends(userID int64) ([]models.Friend, error) {
-	var friends []models.Friend
-	query := `SELECT u.id, u.name FROM users AS u
+// GetFriends retrieves a list of friends for a given user ID, with the friendship metadata, in the given order.
+func (r *friendRepository) GetFriends(userID int64, sort string) ([]models.Friend, error) {
+	query := `SELECT ` + friendLinkColumns + ` FROM users AS u
 			JOIN friend_link AS fl ON u.id = fl.user2_id
-			WHERE fl.user1_id = ?`
+			WHERE fl.user1_id = ? AND u.deactivated_at IS NULL AND ` + notBlockedCondition("u.id") + `
+			` + friendOrderBy(sort)
+	return queryFriendLinks(r.db, query, userID, userID, userID)
+}
 
-	rows, err := r.db.Query(query, userID)
-	if err != nil {
+// GetFriendsInList retrieves the friends of the given user ID who are in one of their friend lists, in the given order.
+// It fails with ErrNotFound when the user has no such list.
+func (r *friendRepository) GetFriendsInList(userID int64, listID int64, sort string) ([]models.Friend, error) {
+	var exists bool
+	existsQuery := `SELECT EXISTS (SELECT 1 FROM friend_lists WHERE id = ? AND owner_id = ?)`
+	if err := r.db.QueryRow(existsQuery, listID, userID).Scan(&exists); err != nil {
 		logutils.Error(err.Error())
 		return nil, err
 	}
-	defer rows.Close()
+	if !exists {
+		logutils.Error("Friend list not found")
+		return nil, ErrNotFound
+	}
 
-	for rows.Next() {
-		var friend models.Friend
-		if err := rows.Scan(&friend.ID, &friend.Name); err != nil {
+	query := `SELECT ` + friendLinkColumns + ` FROM users AS u
+			JOIN friend_list_members AS m ON u.id = m.member_id
+			JOIN friend_link AS fl ON fl.user2_id = u.id
+			WHERE m.list_id = ? AND fl.user1_id = ? AND u.deactivated_at IS NULL AND ` + notBlockedCondition("u.id") + `
+			` + friendOrderBy(sort)
+	return queryFriendLinks(r.db, query, listID, userID, userID, userID)
+}
+
+// GetFriendsPaging retrieves a paginated list of friends for a given user ID in the given order,
+// along with the total number of friends.
+func (r *friendRepository) GetFriendsPaging(userID int64, sort string, limit int, offset int) ([]models.Friend, int, error) {
+	var total int
+	countQuery := `SELECT COUNT(*) FROM friend_link AS fl
+			WHERE fl.user1_id = ? AND ` + activeUserCondition("fl.user2_id") + ` AND ` + notBlockedCondition("fl.user2_id")
+	if err := r.db.QueryRow(countQuery, userID, userID, userID).Scan(&total); err != nil {
+		logutils.Error(err.Error())
+		return nil, 0, err
+	}
+
+	query := `SELECT ` + friendLinkColumns + ` FROM users AS u
+			JOIN friend_link AS fl ON u.id = fl.user2_id
+			WHERE fl.user1_id = ? AND u.deactivated_at IS NULL AND ` + notBlockedCondition("u.id") + `
+			` + friendOrderBy(sort) + `
+			LIMIT ? OFFSET ?`
+	friends, err := queryFriendLinks(r.db, query, userID, userID, userID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return friends, total, nil
+}
+
+// UpdateFriendLink changes the fields of the friendship with friendID on the side of userID.
+// It fails with ErrNotFound when the users are not friends.
+func (r *friendRepository) UpdateFriendLink(userID int64, friendID int64, update models.FriendLinkUpdate) error {
+	var sets []string
+	var args []interface{}
+	if update.CloseFriend != nil {
+		sets = append(sets, "close_friend = ?")
+		args = append(args, *update.CloseFriend)
+	}
+	if update.Nickname != nil {
+		sets = append(sets, "nickname = NULLIF(?, '')")
+		args = append(args, *update.Nickname)
+	}
+
+	if len(sets) > 0 {
+		query := `UPDATE friend_link SET ` + strings.Join(sets, ", ") + ` WHERE user1_id = ? AND user2_id = ?`
+		if _, err := r.db.Exec(query, append(args, userID, friendID)...); err != nil {
 			logutils.Error(err.Error())
-			return nil, err
+			return err
 		}
-		friends = append(friends, friend)
 	}
 
-	// Check for errors from iterating over rows.
-	if err = rows.Err(); err != nil {
+	// Unchanged values affect no row either, so look the friendship up to tell the cases apart
+	var exists bool
+	existsQuery := `SELECT EXISTS (SELECT 1 FROM friend_link WHERE user1_id = ? AND user2_id = ?)`
+	if err := r.db.QueryRow(existsQuery, userID, friendID).Scan(&exists); err != nil {
 		logutils.Error(err.Error())
-		return nil, err
+		return err
+	}
+	if !exists {
+		logutils.Error("Users are not friends")
+		return ErrNotFound
 	}
+	return nil
+}
 
-	return friends, nil
+// GetFriendOfFriendList retrieves a list of two hops friends for a given user ID.
+func (r *friendRepository) GetFriendOfFriendList(userID int64) ([]models.Friend, error) {
+	return r.GetFriendNetwork(userID, 2)
+}
+
+// GetFriendOfFriendListPaging retrieves a paginated list of friends of friends for a given user ID,
+// along with the total number of friends of friends.
+func (r *friendRepository) GetFriendOfFriendListPaging(userID int64, limit int, offset int) ([]models.Friend, int, error) {
+	return r.GetFriendNetworkPaging(userID, 2, limit, offset)
 }
 
-// GetFriendsPaging retrieves a paginated list of friends for a given user ID.
-func (r *friendRepository) GetFriendsPaging(userID int64, limit int, page int) ([]models.Friend, error) {
+// GetMutualFriends retrieves the friends that two users have in common.
+// Nothing is returned when either user has blocked the other. It fails with ErrNotFound when otherID does not exist
+// or is deactivated.
+func (r *friendRepository) GetMutualFriends(userID int64, otherID int64) ([]models.Friend, error) {
+	if err := checkActiveUser(r.db, otherID); err != nil {
+		return nil, err
+	}
+
 	var friends []models.Friend
-	offset := (page - 1) * limit
 	query := `SELECT u.id, u.name FROM users AS u
-			JOIN friend_link AS fl ON u.id = fl.user2_id
-			WHERE fl.user1_id = ?
-			LIMIT ? OFFSET ?`
-
-	rows, err := r.db.Query(query, userID, limit, offset)
+			JOIN friend_link AS fl1 ON u.id = fl1.user2_id
+			JOIN friend_link AS fl2 ON u.id = fl2.user2_id
+			WHERE fl1.user1_id = ? AND fl2.user1_id = ? AND u.deactivated_at IS NULL AND ` + notBlockedCondition("u.id") + `
+			AND NOT EXISTS (
+				SELECT 1 FROM block_list AS ob
+				WHERE (ob.user1_id = fl1.user1_id AND ob.user2_id = fl2.user1_id)
+					OR (ob.user1_id = fl2.user1_id AND ob.user2_id = fl1.user1_id))
+			ORDER BY u.id`
+
+	rows, err := r.db.Query(query, userID, otherID, userID, userID)
 	if err != nil {
 		logutils.Error(err.Error())
 		return nil, err
@@ -266,64 +432,154 @@ func (r *friendRepository) GetFriendsPaging(userID int64, limit int, page int) (
 	return friends, nil
 }
 
-// GetFrineds retrieves a list of two hops friends for a given user ID.
-func (r *friendRepository) GetFriendOfFriendList(userID int64) ([]models.Friend, error) {
+// friendRecommendationSource selects the friends of friends of the bound user together with the friend in between.
+// Users with a pending request in either direction, users blocked in either direction and deactivated users are excluded,
+// and a deactivated friend in between leads nowhere.
+const friendRecommendationSource = `
+	FROM friend_link AS fl1
+	JOIN users AS u1 ON fl1.user2_id = u1.id
+	JOIN friend_link AS fl2 ON fl1.user2_id = fl2.user1_id
+	JOIN users AS u2 ON fl2.user2_id = u2.id
+	WHERE fl1.user1_id = ? AND u2.id != fl1.user1_id AND u1.deactivated_at IS NULL AND u2.deactivated_at IS NULL
+	AND u2.id NOT IN (
+			SELECT user2_id FROM friend_link WHERE user1_id = fl1.user1_id
+	) AND NOT EXISTS (
+			SELECT 1 FROM friend_requests AS fr
+			WHERE fr.status = 'pending'
+			AND ((fr.requester_id = fl1.user1_id AND fr.requested_id = u2.id)
+				OR (fr.requester_id = u2.id AND fr.requested_id = fl1.user1_id))
+	) AND NOT EXISTS (
+			SELECT 1 FROM block_list AS bl
+			WHERE (bl.user1_id = fl1.user1_id AND bl.user2_id = u2.id)
+				OR (bl.user1_id = u2.id AND bl.user2_id = fl1.user1_id)
+	)`
+
+// GetFriendRecommendations retrieves friends of friends ranked by the number of mutual friends,
+// along with the total number of recommendations.
+// Users with a pending request in either direction and users blocked in either direction are excluded.
+func (r *friendRepository) GetFriendRecommendations(userID int64, limit int, offset int) ([]models.Friend, int, error) {
 	var friends []models.Friend
-	query := `SELECT DISTINCT u2.id, u2.name FROM users AS u1
-						JOIN friend_link AS fl1 ON u1.id = fl1.user1_id
-						JOIN friend_link AS fl2 ON fl1.user2_id = fl2.user1_id
-						JOIN users AS u2 ON fl2.user2_id = u2.id
-						LEFT JOIN block_list AS bl ON bl.user1_id = u1.id AND bl.user2_id = u2.id
-						WHERE u1.id = ? AND u2.id != u1.id AND u2.id NOT IN (
-							SELECT user2_id FROM friend_link WHERE user1_id = u1.id
-						) AND bl.user1_id IS NULL`
+	var total int
+	countQuery := `SELECT COUNT(DISTINCT u2.id)` + friendRecommendationSource
+	if err := r.db.QueryRow(countQuery, userID).Scan(&total); err != nil {
+		logutils.Error("Failed to count friend recommendations")
+		logutils.Error(err.Error())
+		return nil, 0, err
+	}
 
-	rows, err := r.db.Query(query, userID)
+	query := `SELECT u2.id, u2.name, COUNT(DISTINCT fl1.user2_id) AS score` + friendRecommendationSource + `
+	GROUP BY u2.id, u2.name
+	ORDER BY score DESC, u2.id
+	LIMIT ? OFFSET ?`
+
+	rows, err := r.db.Query(query, userID, limit, offset)
 	if err != nil {
-		logutils.Error("Failed to get 2 hops friends")
+		logutils.Error("Failed to get friend recommendations")
 		logutils.Error(err.Error())
-		return nil, err
+		return nil, 0, err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
 		var friend models.Friend
-		if err := rows.Scan(&friend.ID, &friend.Name); err != nil {
-			logutils.Error("Failed to scan 2 hops friends")
+		if err := rows.Scan(&friend.ID, &friend.Name, &friend.MutualFriendCount); err != nil {
+			logutils.Error("Failed to scan friend recommendations")
 			logutils.Error(err.Error())
-			return nil, err
+			return nil, 0, err
 		}
 		friends = append(friends, friend)
 	}
 
-	// Check for errors from iterating over rows.
 	if err = rows.Err(); err != nil {
 		logutils.Error("Failed to iterate over rows")
 		logutils.Error(err.Error())
-		return nil, err
+		return nil, 0, err
 	}
-	return friends, nil
+
+	if err := r.attachMutualFriends(userID, friends); err != nil {
+		return nil, 0, err
+	}
+	return friends, total, nil
 }
 
-// GetFriendOfFriendListPaging retrieves a paginated list of friends of friends for a given user ID.
-func (r *friendRepository) GetFriendOfFriendListPaging(userID int64, limit int, offset int) ([]models.Friend, error) {
-	logutils.Info("usrID: " + fmt.Sprint(userID))
-	logutils.Info("limit: " + fmt.Sprint(limit))
-	logutils.Info("offset: " + fmt.Sprint(offset))
+// attachMutualFriends fills in the mutual friends between the given user and each of the two hops friends.
+func (r *friendRepository) attachMutualFriends(userID int64, friends []models.Friend) error {
+	mutuals := make(map[int64][]int64)
+	for start := 0; start < len(friends); start += maxIDsPerQuery {
+		chunk := friends[start:min(start+maxIDsPerQuery, len(friends))]
+
+		args := []interface{}{userID}
+		for _, friend := range chunk {
+			args = append(args, friend.ID)
+		}
+		query := `SELECT fl2.user2_id, fl2.user1_id FROM friend_link AS fl1
+				JOIN friend_link AS fl2 ON fl1.user2_id = fl2.user1_id
+				WHERE fl1.user1_id = ? AND fl2.user2_id IN (` + placeholders(len(chunk)) + `)
+				AND ` + activeUserCondition("fl2.user1_id") + `
+				ORDER BY fl2.user1_id`
+
+		rows, err := r.db.Query(query, args...)
+		if err != nil {
+			logutils.Error("Failed to get mutual friends")
+			logutils.Error(err.Error())
+			return err
+		}
+
+		for rows.Next() {
+			var friendID, mutualID int64
+			if err := rows.Scan(&friendID, &mutualID); err != nil {
+				rows.Close()
+				logutils.Error(err.Error())
+				return err
+			}
+			mutuals[friendID] = append(mutuals[friendID], mutualID)
+		}
+
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			logutils.Error(err.Error())
+			return err
+		}
+	}
+
+	for i := range friends {
+		friends[i].MutualFriendIDs = mutuals[friends[i].ID]
+		friends[i].MutualFriendCount = len(friends[i].MutualFriendIDs)
+	}
+	return nil
+}
+
+// isBlocked reports whether either of the two users has blocked the other.
+func isBlocked(tx *sql.Tx, userID int64, otherID int64) (bool, error) {
+	query := `SELECT EXISTS (
+			SELECT 1 FROM block_list
+			WHERE (user1_id = ? AND user2_id = ?) OR (user1_id = ? AND user2_id = ?))`
+	var blocked bool
+	if err := tx.QueryRow(query, userID, otherID, otherID, userID).Scan(&blocked); err != nil {
+		logutils.Error(err.Error())
+		return false, err
+	}
+	return blocked, nil
+}
+
+// friendLinkColumns selects a friend along with the friendship metadata from users AS u and friend_link AS fl,
+// in the order scanned by queryFriendLinks.
+const friendLinkColumns = `u.id, u.name, UNIX_TIMESTAMP(fl.created_at), fl.close_friend, COALESCE(fl.nickname, '')`
+
+// friendOrderBy returns the ORDER BY clause of a friend list query joining friend_link AS fl.
+func friendOrderBy(sort string) string {
+	if sort == models.FriendSortFriendedAt {
+		return `ORDER BY fl.created_at DESC, u.id`
+	}
+	return `ORDER BY u.id`
+}
+
+// queryFriendLinks runs a query selecting friendLinkColumns and scans the rows into friends.
+func queryFriendLinks(db *sql.DB, query string, args ...interface{}) ([]models.Friend, error) {
 	var friends []models.Friend
-	query := `
-	SELECT DISTINCT u2.id, u2.name 
-	FROM users AS u1
-	JOIN friend_link AS fl1 ON u1.id = fl1.user1_id
-	JOIN friend_link AS fl2 ON fl1.user2_id = fl2.user1_id
-	JOIN users AS u2 ON fl2.user2_id = u2.id
-	LEFT JOIN block_list AS bl ON bl.user1_id = u1.id AND bl.user2_id = u2.id
-	WHERE u1.id = ? AND u2.id != u1.id AND u2.id NOT IN (
-			SELECT user2_id FROM friend_link WHERE user1_id = u1.id
-	) AND bl.user1_id IS NULL
-	LIMIT ? OFFSET ?`
 
-	rows, err := r.db.Query(query, userID, limit, offset)
+	rows, err := db.Query(query, args...)
 	if err != nil {
 		logutils.Error(err.Error())
 		return nil, err
@@ -332,18 +588,17 @@ func (r *friendRepository) GetFriendOfFriendListPaging(userID int64, limit int,
 
 	for rows.Next() {
 		var friend models.Friend
-		if err := rows.Scan(&friend.ID, &friend.Name); err != nil {
-			logutils.Error("Failed to scan 2 hops friends")
+		var friendedAt int64
+		if err := rows.Scan(&friend.ID, &friend.Name, &friendedAt, &friend.CloseFriend, &friend.Nickname); err != nil {
 			logutils.Error(err.Error())
 			return nil, err
 		}
-		logutils.Info("friend: " + fmt.Sprint(friend))
+		t := time.Unix(friendedAt, 0).UTC()
+		friend.FriendedAt = &t
 		friends = append(friends, friend)
 	}
 
-	// Check for errors from iterating over rows.
-	if err = rows.Err(); err != nil {
-		logutils.Error("Failed to iterate over rows")
+	if err := rows.Err(); err != nil {
 		logutils.Error(err.Error())
 		return nil, err
 	}
@@ -351,11 +606,72 @@ func (r *friendRepository) GetFriendOfFriendListPaging(userID int64, limit int,
 	return friends, nil
 }
 
-// DeleteFriend deletes a friend for a given user ID and friend ID.
+// notBlockedCondition returns a WHERE condition that drops rows whose column is a user blocked by,
+// or blocking, the viewer. The viewer ID has to be bound twice.
+func notBlockedCondition(column string) string {
+	return `NOT EXISTS (
+				SELECT 1 FROM block_list AS bl
+				WHERE (bl.user1_id = ? AND bl.user2_id = ` + column + `)
+					OR (bl.user1_id = ` + column + ` AND bl.user2_id = ?))`
+}
+
+// maxIDsPerQuery bounds the number of IDs bound to a single IN clause.
+const maxIDsPerQuery = 1000
+
+// placeholders returns n comma separated bind parameters for an IN clause.
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// int64Args converts IDs into query arguments.
+func int64Args(ids []int64) []interface{} {
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return args
+}
+
+// DeleteFriend ends the friendship between two users: both friend_link rows are removed, the users are removed
+// from each other's friend lists and the accepted friend requests between them are marked as ended in the same transaction.
+// It fails with ErrNotFound when the users were not friends.
 func (r *friendRepository) DeleteFriend(userID int64, friendID int64) error {
-	query := `DELETE FROM friend_link WHERE user1_id = ? AND user2_id = ?`
-	_, err := r.db.Exec(query, userID, friendID)
+	tx, err := r.db.Begin()
+	if err != nil {
+		logutils.Error(err.Error())
+		return err
+	}
+
+	query := `DELETE FROM friend_link WHERE (user1_id = ? AND user2_id = ?) OR (user1_id = ? AND user2_id = ?)`
+	result, err := tx.Exec(query, userID, friendID, friendID, userID)
 	if err != nil {
+		tx.Rollback()
+		logutils.Error(err.Error())
+		return err
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		logutils.Error(err.Error())
+		return err
+	}
+	if deleted == 0 {
+		tx.Rollback()
+		logutils.Error("Users are not friends")
+		return ErrNotFound
+	}
+
+	if err := removeFromFriendLists(tx, userID, friendID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := moveFriendRequests(tx, userID, friendID, models.FriendRequestAccepted, models.FriendRequestEnded); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
 		logutils.Error(err.Error())
 		return err
 	}
@@ -363,22 +679,69 @@ func (r *friendRepository) DeleteFriend(userID int64, friendID int64) error {
 }
 
 // AddBlock adds a user to the block list of another user.
+// Any friendship, friend list membership and follow between the two users are removed and their pending friend
+// requests are canceled in the same transaction.
+// It fails with ErrSelfReference, ErrDuplicate when the user is already blocked and ErrNotFound when a user does not exist.
 func (r *friendRepository) AddBlock(userID int64, blockID int64) error {
-	query := `INSERT INTO block_list (user1_id, user2_id) VALUES (?, ?)`
-	_, err := r.db.Exec(query, userID, blockID)
+	tx, err := r.db.Begin()
 	if err != nil {
 		logutils.Error(err.Error())
 		return err
 	}
+
+	query := `INSERT INTO block_list (user1_id, user2_id) VALUES (?, ?)`
+	if _, err := tx.Exec(query, userID, blockID); err != nil {
+		tx.Rollback()
+		logutils.Error(err.Error())
+		return translateError(err)
+	}
+
+	// Remove the friendship in both directions
+	if err := deleteFriendLinks(tx, userID, blockID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// End the accepted friend requests and cancel the pending ones in both directions
+	if err := moveFriendRequests(tx, userID, blockID, models.FriendRequestAccepted, models.FriendRequestEnded); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := moveFriendRequests(tx, userID, blockID, models.FriendRequestPending, models.FriendRequestCanceled); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// Remove follows in both directions
+	if err := deleteFollows(tx, userID, blockID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		logutils.Error(err.Error())
+		return err
+	}
 	return nil
 }
 
+// deleteFriendLinks removes the friend_link rows between two users in both directions,
+// along with the users from each other's friend lists.
+func deleteFriendLinks(tx *sql.Tx, userID int64, otherID int64) error {
+	query := `DELETE FROM friend_link WHERE (user1_id = ? AND user2_id = ?) OR (user1_id = ? AND user2_id = ?)`
+	if _, err := tx.Exec(query, userID, otherID, otherID, userID); err != nil {
+		logutils.Error(err.Error())
+		return err
+	}
+	return removeFromFriendLists(tx, userID, otherID)
+}
+
 // GetBlockList retrieves a list of users who have been blocked by the given user ID.
 func (r *friendRepository) GetBlockList(userID int64) ([]models.Friend, error) {
 	var blocks []models.Friend
 	query := `SELECT u.id, u.name FROM users AS u
 			JOIN block_list AS bl ON u.id = bl.user2_id
-			WHERE bl.user1_id = ?`
+			WHERE bl.user1_id = ? AND u.deactivated_at IS NULL`
 
 	rows, err := r.db.Query(query, userID)
 	if err != nil {
@@ -0,0 +1,87 @@
+// repository/friend_cursor.go
+
+package repository
+
+import (
+	"database/sql"
+	"minimal_sns_app/domain/models"
+	"minimal_sns_app/logutils"
+)
+
+// The cursor variants of the list queries page through the lists by user ID (keyset pagination),
+// so rows inserted while a client is paging never shift the following pages.
+
+// GetFriendsCursor retrieves up to limit friends of the given user ID whose ID is greater than afterID.
+func (r *friendRepository) GetFriendsCursor(userID int64, afterID int64, limit int) ([]models.Friend, error) {
+	query := `SELECT ` + friendLinkColumns + ` FROM users AS u
+			JOIN friend_link AS fl ON u.id = fl.user2_id
+			WHERE fl.user1_id = ? AND u.id > ? AND u.deactivated_at IS NULL AND ` + notBlockedCondition("u.id") + `
+			ORDER BY u.id
+			LIMIT ?`
+	return queryFriendLinks(r.db, query, userID, afterID, userID, userID, limit)
+}
+
+// GetFriendOfFriendListCursor retrieves up to limit friends of friends of the given user ID whose ID is greater than afterID.
+func (r *friendRepository) GetFriendOfFriendListCursor(userID int64, afterID int64, limit int) ([]models.Friend, error) {
+	return r.GetFriendNetworkCursor(userID, 2, afterID, limit)
+}
+
+// GetFriendRequesterListCursor retrieves up to limit users who have sent a pending friend request to the given user ID
+// whose ID is greater than afterID.
+func (r *friendRepository) GetFriendRequesterListCursor(userID int64, afterID int64, limit int) ([]models.Friend, error) {
+	query := `SELECT u.id, u.name FROM users AS u
+			JOIN friend_requests AS fr ON u.id = fr.requester_id
+			WHERE fr.requested_id = ? AND fr.status = 'pending' AND u.id > ? AND u.deactivated_at IS NULL AND ` + notBlockedCondition("u.id") + `
+			ORDER BY u.id
+			LIMIT ?`
+	return queryFriends(r.db, query, userID, afterID, userID, userID, limit)
+}
+
+// GetFriendRequestedListCursor retrieves up to limit users to whom the given user ID has sent a pending friend request
+// whose ID is greater than afterID.
+func (r *friendRepository) GetFriendRequestedListCursor(userID int64, afterID int64, limit int) ([]models.Friend, error) {
+	query := `SELECT u.id, u.name FROM users AS u
+			JOIN friend_requests AS fr ON u.id = fr.requested_id
+			WHERE fr.requester_id = ? AND fr.status = 'pending' AND u.id > ? AND u.deactivated_at IS NULL AND ` + notBlockedCondition("u.id") + `
+			ORDER BY u.id
+			LIMIT ?`
+	return queryFriends(r.db, query, userID, afterID, userID, userID, limit)
+}
+
+// GetBlockListCursor retrieves up to limit users blocked by the given user ID whose ID is greater than afterID.
+func (r *friendRepository) GetBlockListCursor(userID int64, afterID int64, limit int) ([]models.Friend, error) {
+	query := `SELECT u.id, u.name FROM users AS u
+			JOIN block_list AS bl ON u.id = bl.user2_id
+			WHERE bl.user1_id = ? AND u.id > ? AND u.deactivated_at IS NULL
+			ORDER BY u.id
+			LIMIT ?`
+	return queryFriends(r.db, query, userID, afterID, limit)
+}
+
+// queryFriends runs a query selecting id and name and scans the rows into friends.
+func queryFriends(db *sql.DB, query string, args ...interface{}) ([]models.Friend, error) {
+	var friends []models.Friend
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		logutils.Error(err.Error())
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var friend models.Friend
+		if err := rows.Scan(&friend.ID, &friend.Name); err != nil {
+			logutils.Error(err.Error())
+			return nil, err
+		}
+		friends = append(friends, friend)
+	}
+
+	if err = rows.Err(); err != nil {
+		logutils.Error(err.Error())
+		return nil, err
+	}
+
+	return friends, nil
+}
@@ -0,0 +1,216 @@
+// repository/friend_list.go
+
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"minimal_sns_app/domain/models"
+	"minimal_sns_app/logutils"
+)
+
+// FriendListRepository defines the interface for friend list data access.
+// A list only ever belongs to its owner: a list owned by someone else is reported as ErrNotFound.
+type FriendListRepository interface {
+	CreateFriendList(ownerID int64, name string) (*models.FriendList, error)
+	GetFriendLists(ownerID int64) ([]models.FriendList, error)
+	RenameFriendList(ownerID int64, listID int64, name string) error
+	DeleteFriendList(ownerID int64, listID int64) error
+	AddFriendListMember(ownerID int64, listID int64, friendID int64) error
+	RemoveFriendListMember(ownerID int64, listID int64, friendID int64) error
+}
+
+type friendListRepository struct {
+	db *sql.DB
+}
+
+// NewFriendListRepository creates a new instance of a FriendListRepository.
+func NewFriendListRepository(db *sql.DB) FriendListRepository {
+	return &friendListRepository{db: db}
+}
+
+// CreateFriendList creates an empty list.
+// It fails with ErrDuplicate when the owner already has a list with that name and ErrNotFound when the owner does not exist.
+func (r *friendListRepository) CreateFriendList(ownerID int64, name string) (*models.FriendList, error) {
+	query := `INSERT INTO friend_lists (owner_id, name) VALUES (?, ?)`
+	result, err := r.db.Exec(query, ownerID, name)
+	if err != nil {
+		logutils.Error(err.Error())
+		return nil, translateError(err)
+	}
+
+	listID, err := result.LastInsertId()
+	if err != nil {
+		logutils.Error(err.Error())
+		return nil, err
+	}
+
+	return &models.FriendList{ID: listID, OwnerID: ownerID, Name: name}, nil
+}
+
+// GetFriendLists retrieves the lists of the given owner with their number of members, oldest first.
+func (r *friendListRepository) GetFriendLists(ownerID int64) ([]models.FriendList, error) {
+	var lists []models.FriendList
+	query := `SELECT l.id, l.owner_id, l.name, COUNT(m.member_id) FROM friend_lists AS l
+			LEFT JOIN friend_list_members AS m ON l.id = m.list_id AND ` + activeUserCondition("m.member_id") + `
+			WHERE l.owner_id = ?
+			GROUP BY l.id, l.owner_id, l.name
+			ORDER BY l.id`
+
+	rows, err := r.db.Query(query, ownerID)
+	if err != nil {
+		logutils.Error(err.Error())
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var list models.FriendList
+		if err := rows.Scan(&list.ID, &list.OwnerID, &list.Name, &list.MemberCount); err != nil {
+			logutils.Error(err.Error())
+			return nil, err
+		}
+		lists = append(lists, list)
+	}
+
+	if err := rows.Err(); err != nil {
+		logutils.Error(err.Error())
+		return nil, err
+	}
+
+	return lists, nil
+}
+
+// RenameFriendList renames a list.
+// It fails with ErrNotFound when the owner has no such list and ErrDuplicate when the name is taken by another list.
+func (r *friendListRepository) RenameFriendList(ownerID int64, listID int64, name string) error {
+	query := `UPDATE friend_lists SET name = ? WHERE id = ? AND owner_id = ?`
+	if _, err := r.db.Exec(query, name, listID, ownerID); err != nil {
+		logutils.Error(err.Error())
+		return translateError(err)
+	}
+
+	// An unchanged name affects no row either, so look the list up to tell the cases apart
+	var exists bool
+	existsQuery := `SELECT EXISTS (SELECT 1 FROM friend_lists WHERE id = ? AND owner_id = ?)`
+	if err := r.db.QueryRow(existsQuery, listID, ownerID).Scan(&exists); err != nil {
+		logutils.Error(err.Error())
+		return err
+	}
+	if !exists {
+		logutils.Error("Friend list not found")
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteFriendList deletes a list along with its memberships.
+// It fails with ErrNotFound when the owner has no such list.
+func (r *friendListRepository) DeleteFriendList(ownerID int64, listID int64) error {
+	query := `DELETE FROM friend_lists WHERE id = ? AND owner_id = ?`
+	result, err := r.db.Exec(query, listID, ownerID)
+	if err != nil {
+		logutils.Error(err.Error())
+		return err
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		logutils.Error(err.Error())
+		return err
+	}
+	if deleted == 0 {
+		logutils.Error("Friend list not found")
+		return ErrNotFound
+	}
+	return nil
+}
+
+// AddFriendListMember adds a friend of the owner to one of their lists.
+// It fails with ErrNotFound when the owner has no such list, ErrNotFriends when friendID is not a friend of the owner
+// and ErrDuplicate when the friend is already in the list.
+func (r *friendListRepository) AddFriendListMember(ownerID int64, listID int64, friendID int64) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		logutils.Error(err.Error())
+		return err
+	}
+
+	if err := lockFriendList(tx, ownerID, listID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// Lock the friendship so that a concurrent unfriend waits for the member to be added, then removes it
+	var linked int
+	friendQuery := `SELECT 1 FROM friend_link WHERE user1_id = ? AND user2_id = ? FOR SHARE`
+	if err := tx.QueryRow(friendQuery, ownerID, friendID).Scan(&linked); err != nil {
+		tx.Rollback()
+		logutils.Error(err.Error())
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFriends
+		}
+		return err
+	}
+
+	query := `INSERT INTO friend_list_members (list_id, member_id) VALUES (?, ?)`
+	if _, err := tx.Exec(query, listID, friendID); err != nil {
+		tx.Rollback()
+		logutils.Error(err.Error())
+		return translateError(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		logutils.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+// RemoveFriendListMember removes a member from one of the owner's lists.
+// It fails with ErrNotFound when the owner has no such list or the user is not in it.
+func (r *friendListRepository) RemoveFriendListMember(ownerID int64, listID int64, friendID int64) error {
+	query := `DELETE m FROM friend_list_members AS m
+			JOIN friend_lists AS l ON m.list_id = l.id
+			WHERE m.list_id = ? AND m.member_id = ? AND l.owner_id = ?`
+	result, err := r.db.Exec(query, listID, friendID, ownerID)
+	if err != nil {
+		logutils.Error(err.Error())
+		return err
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		logutils.Error(err.Error())
+		return err
+	}
+	if deleted == 0 {
+		logutils.Error("Friend list member not found")
+		return ErrNotFound
+	}
+	return nil
+}
+
+// lockFriendList locks a list of the owner. It fails with ErrNotFound when the owner has no such list.
+func lockFriendList(tx *sql.Tx, ownerID int64, listID int64) error {
+	var id int64
+	query := `SELECT id FROM friend_lists WHERE id = ? AND owner_id = ? FOR UPDATE`
+	if err := tx.QueryRow(query, listID, ownerID).Scan(&id); err != nil {
+		logutils.Error(err.Error())
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// removeFromFriendLists removes two users from each other's lists, once they are no longer friends.
+func removeFromFriendLists(tx *sql.Tx, userID int64, otherID int64) error {
+	query := `DELETE m FROM friend_list_members AS m
+			JOIN friend_lists AS l ON m.list_id = l.id
+			WHERE (l.owner_id = ? AND m.member_id = ?) OR (l.owner_id = ? AND m.member_id = ?)`
+	if _, err := tx.Exec(query, userID, otherID, otherID, userID); err != nil {
+		logutils.Error(err.Error())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+// repository/friend_network.go
+
+package repository
+
+import (
+	"minimal_sns_app/domain/models"
+	"minimal_sns_app/logutils"
+	"sort"
+)
+
+// GetFriendNetwork retrieves the users exactly depth hops away from the given user ID.
+// Users blocked by, or blocking, the given user are excluded. For depth 2 each user carries the mutual friends it is reached through.
+func (r *friendRepository) GetFriendNetwork(userID int64, depth int) ([]models.Friend, error) {
+	ids, via, err := r.getFriendNetworkIDs(userID, depth)
+	if err != nil {
+		return nil, err
+	}
+	return r.getFriendNetworkUsers(ids, via, depth)
+}
+
+// GetFriendNetworkPaging retrieves a paginated list of the users exactly depth hops away from the given user ID,
+// along with the total number of such users.
+func (r *friendRepository) GetFriendNetworkPaging(userID int64, depth int, limit int, offset int) ([]models.Friend, int, error) {
+	ids, via, err := r.getFriendNetworkIDs(userID, depth)
+	if err != nil {
+		return nil, 0, err
+	}
+	total := len(ids)
+	ids = ids[min(offset, total):min(offset+limit, total)]
+	friends, err := r.getFriendNetworkUsers(ids, via, depth)
+	if err != nil {
+		return nil, 0, err
+	}
+	return friends, total, nil
+}
+
+// GetFriendNetworkCursor retrieves up to limit users exactly depth hops away from the given user ID whose ID is greater than afterID.
+func (r *friendRepository) GetFriendNetworkCursor(userID int64, depth int, afterID int64, limit int) ([]models.Friend, error) {
+	ids, via, err := r.getFriendNetworkIDs(userID, depth)
+	if err != nil {
+		return nil, err
+	}
+	start := sort.Search(len(ids), func(i int) bool { return ids[i] > afterID })
+	ids = ids[start:min(start+limit, len(ids))]
+	return r.getFriendNetworkUsers(ids, via, depth)
+}
+
+// getFriendNetworkIDs collects the IDs of the users at the given distance in ascending order,
+// along with the users one hop closer that lead to each of them.
+func (r *friendRepository) getFriendNetworkIDs(userID int64, depth int) ([]int64, map[int64][]int64, error) {
+	blocked, err := r.getBlockedIDs(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	via, err := r.getUsersAtDistance(userID, depth, blocked)
+	if err != nil {
+		logutils.Error("Failed to get friend network")
+		return nil, nil, err
+	}
+
+	var ids []int64
+	for id := range via {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids, via, nil
+}
+
+// getFriendNetworkUsers retrieves the users for the given network IDs.
+func (r *friendRepository) getFriendNetworkUsers(ids []int64, via map[int64][]int64, depth int) ([]models.Friend, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	friends, err := r.getFriendsByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only at two hops are the users in between the mutual friends.
+	if depth == 2 {
+		for i := range friends {
+			mutuals := via[friends[i].ID]
+			sort.Slice(mutuals, func(a, b int) bool { return mutuals[a] < mutuals[b] })
+			friends[i].MutualFriendIDs = mutuals
+			friends[i].MutualFriendCount = len(mutuals)
+		}
+	}
+	return friends, nil
+}
+
+// getUsersAtDistance walks friend_link breadth first from the given user ID and returns the users
+// whose shortest distance is exactly depth, each with the users one hop closer that lead to it.
+// Blocked users are skipped as GetFriendPath skips them: they are neither returned nor walked through.
+func (r *friendRepository) getUsersAtDistance(userID int64, depth int, blocked map[int64]bool) (map[int64][]int64, error) {
+	visited := map[int64]bool{userID: true}
+	frontier := []int64{userID}
+	var level map[int64][]int64
+
+	for d := 0; d < depth; d++ {
+		if len(frontier) == 0 {
+			return nil, nil
+		}
+
+		links, err := r.getFriendLinks(frontier, true)
+		if err != nil {
+			return nil, err
+		}
+
+		level = make(map[int64][]int64)
+		for _, link := range links {
+			from, to := link[0], link[1]
+			if visited[to] || blocked[to] {
+				continue
+			}
+			level[to] = append(level[to], from)
+		}
+
+		frontier = frontier[:0]
+		for id := range level {
+			visited[id] = true
+			frontier = append(frontier, id)
+		}
+	}
+
+	return level, nil
+}
+
+// getBlockedIDs retrieves the set of users blocked by, or blocking, the given user ID.
+func (r *friendRepository) getBlockedIDs(userID int64) (map[int64]bool, error) {
+	query := `SELECT user2_id FROM block_list WHERE user1_id = ?
+			UNION SELECT user1_id FROM block_list WHERE user2_id = ?`
+	rows, err := r.db.Query(query, userID, userID)
+	if err != nil {
+		logutils.Error(err.Error())
+		return nil, err
+	}
+	defer rows.Close()
+
+	blocked := make(map[int64]bool)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			logutils.Error(err.Error())
+			return nil, err
+		}
+		blocked[id] = true
+	}
+
+	if err = rows.Err(); err != nil {
+		logutils.Error(err.Error())
+		return nil, err
+	}
+
+	return blocked, nil
+}
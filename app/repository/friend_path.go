@@ -0,0 +1,186 @@
+// repository/friend_path.go
+
+package repository
+
+import (
+	"minimal_sns_app/domain/models"
+	"minimal_sns_app/logutils"
+)
+
+// GetFriendPath retrieves the shortest chain of friends from userID to targetID, both ends included.
+// It runs a bidirectional breadth first search over friend_link and gives up after maxDepth hops.
+// A nil path is returned when the users are not connected within maxDepth.
+// Users blocked by, or blocking, userID can neither be reached nor be passed through.
+func (r *friendRepository) GetFriendPath(userID int64, targetID int64, maxDepth int) ([]models.Friend, error) {
+	if userID == targetID {
+		return r.getFriendPathUsers([]int64{userID})
+	}
+
+	blocked, err := r.getBlockedIDs(userID)
+	if err != nil {
+		return nil, err
+	}
+	if blocked[targetID] {
+		return nil, nil
+	}
+
+	// prev links each node reached from userID to the node it was reached from,
+	// next links each node reached from targetID to the node it leads to.
+	prev := map[int64]int64{userID: userID}
+	next := map[int64]int64{targetID: targetID}
+	forward := []int64{userID}
+	backward := []int64{targetID}
+
+	for depth := 0; depth < maxDepth && len(forward) > 0 && len(backward) > 0; depth++ {
+		var meet int64
+		var found bool
+
+		// Always expand the smaller frontier to keep the queries small.
+		if len(forward) <= len(backward) {
+			forward, meet, found, err = r.expandFrontier(forward, prev, next, blocked, true)
+		} else {
+			backward, meet, found, err = r.expandFrontier(backward, next, prev, blocked, false)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			return r.getFriendPathUsers(joinPath(meet, prev, next))
+		}
+	}
+
+	return nil, nil
+}
+
+// expandFrontier visits every neighbor of the frontier that has not been seen yet on this side of the search.
+// It stops as soon as a neighbor already seen from the other side is reached and returns it as the meeting point.
+// Blocked neighbors are skipped, so that no path goes through them.
+func (r *friendRepository) expandFrontier(frontier []int64, seen map[int64]int64, other map[int64]int64, blocked map[int64]bool, forward bool) ([]int64, int64, bool, error) {
+	links, err := r.getFriendLinks(frontier, forward)
+	if err != nil {
+		logutils.Error("Failed to expand friend path frontier")
+		return nil, 0, false, err
+	}
+
+	var nextFrontier []int64
+	for _, link := range links {
+		from, to := link[0], link[1]
+		if _, ok := seen[to]; ok || blocked[to] {
+			continue
+		}
+		seen[to] = from
+		if _, ok := other[to]; ok {
+			return nil, to, true, nil
+		}
+		nextFrontier = append(nextFrontier, to)
+	}
+
+	return nextFrontier, 0, false, nil
+}
+
+// getFriendLinks retrieves the friend links leaving the given users as (from, to) pairs.
+// With forward set to false the links arriving at the given users are followed in reverse instead.
+// Links to deactivated users are skipped, so that nobody is reached through them.
+func (r *friendRepository) getFriendLinks(ids []int64, forward bool) ([][2]int64, error) {
+	var links [][2]int64
+	for start := 0; start < len(ids); start += maxIDsPerQuery {
+		chunk := ids[start:min(start+maxIDsPerQuery, len(ids))]
+
+		query := `SELECT user1_id, user2_id FROM friend_link
+				WHERE user1_id IN (` + placeholders(len(chunk)) + `) AND ` + activeUserCondition("user2_id")
+		if !forward {
+			query = `SELECT user2_id, user1_id FROM friend_link
+				WHERE user2_id IN (` + placeholders(len(chunk)) + `) AND ` + activeUserCondition("user1_id")
+		}
+
+		rows, err := r.db.Query(query, int64Args(chunk)...)
+		if err != nil {
+			logutils.Error(err.Error())
+			return nil, err
+		}
+
+		for rows.Next() {
+			var link [2]int64
+			if err := rows.Scan(&link[0], &link[1]); err != nil {
+				rows.Close()
+				logutils.Error(err.Error())
+				return nil, err
+			}
+			links = append(links, link)
+		}
+
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			logutils.Error(err.Error())
+			return nil, err
+		}
+	}
+	return links, nil
+}
+
+// joinPath builds the path through the meeting point from the links recorded by both sides of the search.
+func joinPath(meet int64, prev map[int64]int64, next map[int64]int64) []int64 {
+	var path []int64
+	for id := meet; ; id = prev[id] {
+		path = append([]int64{id}, path...)
+		if prev[id] == id {
+			break
+		}
+	}
+	for id := meet; next[id] != id; {
+		id = next[id]
+		path = append(path, id)
+	}
+	return path
+}
+
+// getFriendPathUsers retrieves the users along a path, or nil when one of them no longer exists.
+func (r *friendRepository) getFriendPathUsers(path []int64) ([]models.Friend, error) {
+	friends, err := r.getFriendsByIDs(path)
+	if err != nil || len(friends) != len(path) {
+		return nil, err
+	}
+	return friends, nil
+}
+
+// getFriendsByIDs retrieves the users with the given IDs, keeping the order of the IDs.
+// IDs of users that do not exist or are deactivated are skipped.
+func (r *friendRepository) getFriendsByIDs(ids []int64) ([]models.Friend, error) {
+	names := make(map[int64]string)
+	for start := 0; start < len(ids); start += maxIDsPerQuery {
+		chunk := ids[start:min(start+maxIDsPerQuery, len(ids))]
+		query := `SELECT id, name FROM users WHERE id IN (` + placeholders(len(chunk)) + `) AND deactivated_at IS NULL`
+
+		rows, err := r.db.Query(query, int64Args(chunk)...)
+		if err != nil {
+			logutils.Error(err.Error())
+			return nil, err
+		}
+
+		for rows.Next() {
+			var friend models.Friend
+			if err := rows.Scan(&friend.ID, &friend.Name); err != nil {
+				rows.Close()
+				logutils.Error(err.Error())
+				return nil, err
+			}
+			names[friend.ID] = friend.Name
+		}
+
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			logutils.Error(err.Error())
+			return nil, err
+		}
+	}
+
+	friends := make([]models.Friend, 0, len(ids))
+	for _, id := range ids {
+		if name, ok := names[id]; ok {
+			friends = append(friends, models.Friend{ID: id, Name: name})
+		}
+	}
+	return friends, nil
+}
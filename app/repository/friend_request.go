@@ -0,0 +1,341 @@
+// repository/friend_request.go
+
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"minimal_sns_app/domain/models"
+	"minimal_sns_app/logutils"
+	"time"
+)
+
+// friendRequestCooldown is how long a user has to wait before sending a new request to a user who declined the last one.
+const friendRequestCooldown = 24 * time.Hour
+
+// A friend request row is never deleted by the lifecycle: it moves from pending to accepted, declined, canceled or expired,
+// from accepted to ended when the friendship ends, and every move is recorded in friend_request_events. Only one request per direction can be pending at a time,
+// so a new request can be sent once the previous one has been answered.
+
+// insertFriendRequest creates a pending friend request, records its first transition and returns its ID.
+func insertFriendRequest(tx *sql.Tx, requesterID int64, requestedID int64, message string) (int64, error) {
+	query := `INSERT INTO friend_requests (requester_id, requested_id, message) VALUES (?, ?, ?)`
+	result, err := tx.Exec(query, requesterID, requestedID, message)
+	if err != nil {
+		logutils.Error(err.Error())
+		return 0, translateError(err)
+	}
+	requestID, err := result.LastInsertId()
+	if err != nil {
+		logutils.Error(err.Error())
+		return 0, err
+	}
+	if err := recordFriendRequestEvent(tx, requestID, models.FriendRequestPending); err != nil {
+		return 0, err
+	}
+	return requestID, nil
+}
+
+// lockPendingFriendRequest locks the latest friend request from requesterID to requestedID and returns its ID.
+// It fails with ErrNotFound when there is no request and ErrNotPending when the latest one was already answered.
+func lockPendingFriendRequest(tx *sql.Tx, requesterID int64, requestedID int64) (int64, error) {
+	query := `SELECT id, status FROM friend_requests
+			WHERE requester_id = ? AND requested_id = ?
+			ORDER BY id DESC
+			LIMIT 1
+			FOR UPDATE`
+	var requestID int64
+	var status string
+	if err := tx.QueryRow(query, requesterID, requestedID).Scan(&requestID, &status); err != nil {
+		logutils.Error(err.Error())
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrNotFound
+		}
+		return 0, err
+	}
+
+	if status != models.FriendRequestPending {
+		logutils.Error("Friend request is not pending")
+		return 0, ErrNotPending
+	}
+	return requestID, nil
+}
+
+// lockCrossedFriendRequest locks the pending friend request sent by requestedID to requesterID, if any,
+// and returns its ID. The lock keeps the request from being canceled or declined while it is accepted;
+// a crossed request sent at the same time is kept out by the lockUserPair call of RequestFriend instead.
+func lockCrossedFriendRequest(tx *sql.Tx, requesterID int64, requestedID int64) (int64, bool, error) {
+	query := `SELECT id FROM friend_requests
+			WHERE requester_id = ? AND requested_id = ? AND status = 'pending'
+			FOR UPDATE`
+	var requestID int64
+	if err := tx.QueryRow(query, requestedID, requesterID).Scan(&requestID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, false, nil
+		}
+		logutils.Error(err.Error())
+		return 0, false, err
+	}
+	return requestID, true, nil
+}
+
+// acceptCrossedFriendRequest makes two users friends when requesterID requests requestedID while
+// the crossed request crossedID is pending. Both requests end up accepted.
+func acceptCrossedFriendRequest(tx *sql.Tx, requesterID int64, requestedID int64, message string, crossedID int64) error {
+	requestID, err := insertFriendRequest(tx, requesterID, requestedID, message)
+	if err != nil {
+		return err
+	}
+
+	insertQuery := `INSERT INTO friend_link (user1_id, user2_id) VALUES (?, ?), (?, ?)`
+	if _, err := tx.Exec(insertQuery, requesterID, requestedID, requestedID, requesterID); err != nil {
+		logutils.Error(err.Error())
+		if err = translateError(err); errors.Is(err, ErrDuplicate) {
+			return ErrAlreadyFriends
+		}
+		return err
+	}
+
+	for _, id := range []int64{crossedID, requestID} {
+		if err := setFriendRequestStatus(tx, id, models.FriendRequestAccepted); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setFriendRequestStatus moves a friend request to status and records the transition.
+func setFriendRequestStatus(tx *sql.Tx, requestID int64, status string) error {
+	query := `UPDATE friend_requests SET status = ? WHERE id = ?`
+	if _, err := tx.Exec(query, status, requestID); err != nil {
+		logutils.Error(err.Error())
+		return err
+	}
+	return recordFriendRequestEvent(tx, requestID, status)
+}
+
+// recordFriendRequestEvent records that a friend request entered status.
+func recordFriendRequestEvent(tx *sql.Tx, requestID int64, status string) error {
+	query := `INSERT INTO friend_request_events (request_id, status) VALUES (?, ?)`
+	if _, err := tx.Exec(query, requestID, status); err != nil {
+		logutils.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+// moveFriendRequests moves the friend requests between two users in both directions from one status to another
+// and records the transitions.
+func moveFriendRequests(tx *sql.Tx, userID int64, otherID int64, from string, to string) error {
+	eventQuery := `INSERT INTO friend_request_events (request_id, status)
+			SELECT id, ? FROM friend_requests
+			WHERE status = ? AND ((requester_id = ? AND requested_id = ?) OR (requester_id = ? AND requested_id = ?))`
+	if _, err := tx.Exec(eventQuery, to, from, userID, otherID, otherID, userID); err != nil {
+		logutils.Error(err.Error())
+		return err
+	}
+
+	updateQuery := `UPDATE friend_requests SET status = ?
+			WHERE status = ? AND ((requester_id = ? AND requested_id = ?) OR (requester_id = ? AND requested_id = ?))`
+	if _, err := tx.Exec(updateQuery, to, from, userID, otherID, otherID, userID); err != nil {
+		logutils.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+// inFriendRequestCooldown reports whether requestedID declined a request from requesterID within friendRequestCooldown.
+func inFriendRequestCooldown(tx *sql.Tx, requesterID int64, requestedID int64) (bool, error) {
+	var cooling bool
+	query := `SELECT EXISTS (
+			SELECT 1 FROM friend_requests
+			WHERE requester_id = ? AND requested_id = ? AND status = 'declined'
+			AND updated_at > NOW() - INTERVAL ? SECOND
+	)`
+	if err := tx.QueryRow(query, requesterID, requestedID, int64(friendRequestCooldown.Seconds())).Scan(&cooling); err != nil {
+		logutils.Error(err.Error())
+		return false, err
+	}
+	return cooling, nil
+}
+
+// queryFriendRequestUsers runs a query selecting the user id and name followed by the request id, status, message
+// and created_at as a Unix timestamp, and scans the rows.
+func (r *friendRepository) queryFriendRequestUsers(query string, args ...interface{}) ([]models.FriendRequestUser, error) {
+	var users []models.FriendRequestUser
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		logutils.Error(err.Error())
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var user models.FriendRequestUser
+		var createdAt int64
+		if err := rows.Scan(&user.ID, &user.Name, &user.RequestID, &user.Status, &user.Message, &createdAt); err != nil {
+			logutils.Error(err.Error())
+			return nil, err
+		}
+		user.CreatedAt = time.Unix(createdAt, 0).UTC()
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		logutils.Error(err.Error())
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// GetFriendRequestHistory retrieves the friend requests sent between two users in either direction, oldest first,
+// each with the transitions it went through. It fails with ErrNotFound when otherID does not exist or is deactivated.
+func (r *friendRepository) GetFriendRequestHistory(userID int64, otherID int64) ([]models.FriendRequest, error) {
+	if err := checkActiveUser(r.db, otherID); err != nil {
+		return nil, err
+	}
+
+	var requests []models.FriendRequest
+	query := `SELECT id, requester_id, requested_id, status, UNIX_TIMESTAMP(created_at), UNIX_TIMESTAMP(updated_at)
+			FROM friend_requests
+			WHERE (requester_id = ? AND requested_id = ?) OR (requester_id = ? AND requested_id = ?)
+			ORDER BY id`
+
+	rows, err := r.db.Query(query, userID, otherID, otherID, userID)
+	if err != nil {
+		logutils.Error(err.Error())
+		return nil, err
+	}
+	defer rows.Close()
+
+	index := make(map[int64]int)
+	for rows.Next() {
+		var request models.FriendRequest
+		var createdAt, updatedAt int64
+		if err := rows.Scan(&request.ID, &request.RequesterID, &request.RequestedID, &request.Status, &createdAt, &updatedAt); err != nil {
+			logutils.Error(err.Error())
+			return nil, err
+		}
+		request.CreatedAt = time.Unix(createdAt, 0).UTC()
+		request.UpdatedAt = time.Unix(updatedAt, 0).UTC()
+		request.Events = []models.FriendRequestEvent{}
+		index[request.ID] = len(requests)
+		requests = append(requests, request)
+	}
+
+	if err := rows.Err(); err != nil {
+		logutils.Error(err.Error())
+		return nil, err
+	}
+
+	if len(requests) == 0 {
+		return requests, nil
+	}
+
+	eventQuery := `SELECT e.request_id, e.status, UNIX_TIMESTAMP(e.created_at)
+			FROM friend_request_events AS e
+			JOIN friend_requests AS fr ON e.request_id = fr.id
+			WHERE (fr.requester_id = ? AND fr.requested_id = ?) OR (fr.requester_id = ? AND fr.requested_id = ?)
+			ORDER BY e.id`
+
+	eventRows, err := r.db.Query(eventQuery, userID, otherID, otherID, userID)
+	if err != nil {
+		logutils.Error(err.Error())
+		return nil, err
+	}
+	defer eventRows.Close()
+
+	for eventRows.Next() {
+		var requestID, createdAt int64
+		var event models.FriendRequestEvent
+		if err := eventRows.Scan(&requestID, &event.Status, &createdAt); err != nil {
+			logutils.Error(err.Error())
+			return nil, err
+		}
+		event.CreatedAt = time.Unix(createdAt, 0).UTC()
+		if i, ok := index[requestID]; ok {
+			requests[i].Events = append(requests[i].Events, event)
+		}
+	}
+
+	if err := eventRows.Err(); err != nil {
+		logutils.Error(err.Error())
+		return nil, err
+	}
+
+	return requests, nil
+}
+
+// ExpireFriendRequests expires the pending friend requests created more than ttl ago and returns how many were expired.
+// The requests are updated batchSize at a time, each batch in its own transaction, so that a large backlog
+// does not hold locks on the whole table. A batch size that is not positive returns ErrInvalidBatchSize.
+func (r *friendRepository) ExpireFriendRequests(ttl time.Duration, batchSize int) (int, error) {
+	if batchSize <= 0 {
+		return 0, ErrInvalidBatchSize
+	}
+	expired := 0
+	for {
+		n, err := r.expireFriendRequestBatch(ttl, batchSize)
+		if err != nil {
+			return expired, err
+		}
+		expired += n
+		if n < batchSize {
+			return expired, nil
+		}
+	}
+}
+
+// expireFriendRequestBatch expires up to batchSize stale pending friend requests in one transaction.
+func (r *friendRepository) expireFriendRequestBatch(ttl time.Duration, batchSize int) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		logutils.Error(err.Error())
+		return 0, err
+	}
+
+	query := `SELECT id FROM friend_requests
+			WHERE status = 'pending' AND created_at < NOW() - INTERVAL ? SECOND
+			ORDER BY id
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED`
+	rows, err := tx.Query(query, int64(ttl.Seconds()), batchSize)
+	if err != nil {
+		tx.Rollback()
+		logutils.Error(err.Error())
+		return 0, err
+	}
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			tx.Rollback()
+			logutils.Error(err.Error())
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		tx.Rollback()
+		logutils.Error(err.Error())
+		return 0, err
+	}
+
+	for _, id := range ids {
+		if err := setFriendRequestStatus(tx, id, models.FriendRequestExpired); err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		logutils.Error(err.Error())
+		return 0, err
+	}
+	return len(ids), nil
+}
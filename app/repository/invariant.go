@@ -0,0 +1,227 @@
+// repository/invariant.go
+
+package repository
+
+import (
+	"database/sql"
+	"minimal_sns_app/domain/models"
+	"minimal_sns_app/logutils"
+)
+
+// InvariantRepository defines the interface for checking and repairing the consistency of the friendship tables.
+type InvariantRepository interface {
+	CheckFriendships(limit int) (*models.FriendshipReport, error)
+	RepairFriendships(batchSize int) (map[string]int, error)
+}
+
+type invariantRepository struct {
+	db *sql.DB
+}
+
+// NewInvariantRepository creates a new instance of an InvariantRepository.
+func NewInvariantRepository(db *sql.DB) InvariantRepository {
+	return &invariantRepository{db: db}
+}
+
+// invariantCheck finds the rows breaking one invariant and repairs them one by one.
+// find selects request_id (0 for rows outside friend_requests), user1_id and user2_id, without ORDER BY or LIMIT.
+type invariantCheck struct {
+	kind   string
+	find   string
+	repair func(tx *sql.Tx, inconsistency models.Inconsistency) error
+}
+
+// invariantChecks lists the checks in the order they are repaired: removing links between blocked users first
+// keeps them from being reported as one-sided, and ending links first lets their accepted requests be ended
+// and leaves only the list members whose friendship is really gone.
+var invariantChecks = []invariantCheck{
+	{
+		kind: models.InconsistencyBlockedLink,
+		find: `SELECT 0 AS request_id, fl.user1_id AS user1_id, fl.user2_id AS user2_id FROM friend_link AS fl
+				WHERE EXISTS (
+					SELECT 1 FROM block_list AS bl
+					WHERE (bl.user1_id = fl.user1_id AND bl.user2_id = fl.user2_id)
+						OR (bl.user1_id = fl.user2_id AND bl.user2_id = fl.user1_id)
+				)`,
+		// Same as AddBlock: remove the friendship and the follows, end the accepted requests and cancel the pending ones
+		repair: func(tx *sql.Tx, inconsistency models.Inconsistency) error {
+			if err := deleteFriendLinks(tx, inconsistency.User1ID, inconsistency.User2ID); err != nil {
+				return err
+			}
+			if err := moveFriendRequests(tx, inconsistency.User1ID, inconsistency.User2ID, models.FriendRequestAccepted, models.FriendRequestEnded); err != nil {
+				return err
+			}
+			if err := moveFriendRequests(tx, inconsistency.User1ID, inconsistency.User2ID, models.FriendRequestPending, models.FriendRequestCanceled); err != nil {
+				return err
+			}
+			return deleteFollows(tx, inconsistency.User1ID, inconsistency.User2ID)
+		},
+	},
+	{
+		kind: models.InconsistencyBlockedFollow,
+		find: `SELECT 0 AS request_id, f.follower_id AS user1_id, f.followee_id AS user2_id FROM follow AS f
+				WHERE EXISTS (
+					SELECT 1 FROM block_list AS bl
+					WHERE (bl.user1_id = f.follower_id AND bl.user2_id = f.followee_id)
+						OR (bl.user1_id = f.followee_id AND bl.user2_id = f.follower_id)
+				)`,
+		repair: func(tx *sql.Tx, inconsistency models.Inconsistency) error {
+			return deleteFollows(tx, inconsistency.User1ID, inconsistency.User2ID)
+		},
+	},
+	{
+		kind: models.InconsistencyOneSidedLink,
+		find: `SELECT 0 AS request_id, fl.user1_id AS user1_id, fl.user2_id AS user2_id FROM friend_link AS fl
+				LEFT JOIN friend_link AS rl ON rl.user1_id = fl.user2_id AND rl.user2_id = fl.user1_id
+				WHERE rl.user1_id IS NULL`,
+		// A one-sided link is what an unfriend used to leave behind, so the friendship is ended
+		repair: func(tx *sql.Tx, inconsistency models.Inconsistency) error {
+			if err := deleteFriendLinks(tx, inconsistency.User1ID, inconsistency.User2ID); err != nil {
+				return err
+			}
+			return moveFriendRequests(tx, inconsistency.User1ID, inconsistency.User2ID, models.FriendRequestAccepted, models.FriendRequestEnded)
+		},
+	},
+	{
+		kind: models.InconsistencyAcceptedWithoutLink,
+		find: `SELECT fr.id AS request_id, fr.requester_id AS user1_id, fr.requested_id AS user2_id FROM friend_requests AS fr
+				WHERE fr.status = 'accepted' AND NOT EXISTS (
+					SELECT 1 FROM friend_link AS fl
+					WHERE (fl.user1_id = fr.requester_id AND fl.user2_id = fr.requested_id)
+						OR (fl.user1_id = fr.requested_id AND fl.user2_id = fr.requester_id)
+				)`,
+		repair: func(tx *sql.Tx, inconsistency models.Inconsistency) error {
+			return setFriendRequestStatus(tx, inconsistency.RequestID, models.FriendRequestEnded)
+		},
+	},
+	{
+		kind: models.InconsistencyPendingBetweenFriends,
+		find: `SELECT fr.id AS request_id, fr.requester_id AS user1_id, fr.requested_id AS user2_id FROM friend_requests AS fr
+				JOIN friend_link AS fl ON fl.user1_id = fr.requester_id AND fl.user2_id = fr.requested_id
+				WHERE fr.status = 'pending'`,
+		repair: func(tx *sql.Tx, inconsistency models.Inconsistency) error {
+			return setFriendRequestStatus(tx, inconsistency.RequestID, models.FriendRequestCanceled)
+		},
+	},
+	{
+		kind: models.InconsistencyListMemberNotFriend,
+		find: `SELECT 0 AS request_id, l.owner_id AS user1_id, m.member_id AS user2_id FROM friend_list_members AS m
+				JOIN friend_lists AS l ON m.list_id = l.id
+				WHERE NOT EXISTS (SELECT 1 FROM friend_link AS fl WHERE fl.user1_id = l.owner_id AND fl.user2_id = m.member_id)`,
+		// Same as unfriending: the users leave each other's lists
+		repair: func(tx *sql.Tx, inconsistency models.Inconsistency) error {
+			return removeFromFriendLists(tx, inconsistency.User1ID, inconsistency.User2ID)
+		},
+	},
+}
+
+// CheckFriendships counts the inconsistencies of every kind and lists up to limit of each.
+func (r *invariantRepository) CheckFriendships(limit int) (*models.FriendshipReport, error) {
+	report := &models.FriendshipReport{
+		Counts:          make(map[string]int),
+		Inconsistencies: []models.Inconsistency{},
+	}
+
+	for _, check := range invariantChecks {
+		var count int
+		if err := r.db.QueryRow(`SELECT COUNT(*) FROM (` + check.find + `) AS t`).Scan(&count); err != nil {
+			logutils.Error(err.Error())
+			return nil, err
+		}
+		report.Counts[check.kind] = count
+
+		if count == 0 || limit <= 0 {
+			continue
+		}
+		rows, err := r.db.Query(check.find+` ORDER BY user1_id, user2_id, request_id LIMIT ?`, limit)
+		if err != nil {
+			logutils.Error(err.Error())
+			return nil, err
+		}
+		inconsistencies, err := scanInconsistencies(rows, check.kind)
+		if err != nil {
+			return nil, err
+		}
+		report.Inconsistencies = append(report.Inconsistencies, inconsistencies...)
+	}
+
+	return report, nil
+}
+
+// RepairFriendships repairs every inconsistency, batchSize rows per transaction, and returns how many
+// of every kind were repaired. A batch size that is not positive returns ErrInvalidBatchSize, since a batch
+// of no rows could never finish the repair.
+func (r *invariantRepository) RepairFriendships(batchSize int) (map[string]int, error) {
+	if batchSize <= 0 {
+		return nil, ErrInvalidBatchSize
+	}
+	repaired := make(map[string]int)
+	for _, check := range invariantChecks {
+		for {
+			n, err := r.repairBatch(check, batchSize)
+			repaired[check.kind] += n
+			if err != nil {
+				return repaired, err
+			}
+			if n < batchSize {
+				break
+			}
+		}
+	}
+	return repaired, nil
+}
+
+// repairBatch locks and repairs up to batchSize inconsistencies of one kind in one transaction.
+func (r *invariantRepository) repairBatch(check invariantCheck, batchSize int) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		logutils.Error(err.Error())
+		return 0, err
+	}
+
+	rows, err := tx.Query(check.find+` ORDER BY user1_id, user2_id, request_id LIMIT ? FOR UPDATE`, batchSize)
+	if err != nil {
+		tx.Rollback()
+		logutils.Error(err.Error())
+		return 0, err
+	}
+	inconsistencies, err := scanInconsistencies(rows, check.kind)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	for _, inconsistency := range inconsistencies {
+		if err := check.repair(tx, inconsistency); err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		logutils.Error(err.Error())
+		return 0, err
+	}
+	return len(inconsistencies), nil
+}
+
+// scanInconsistencies scans and closes rows selecting request_id, user1_id and user2_id.
+func scanInconsistencies(rows *sql.Rows, kind string) ([]models.Inconsistency, error) {
+	defer rows.Close()
+
+	var inconsistencies []models.Inconsistency
+	for rows.Next() {
+		inconsistency := models.Inconsistency{Kind: kind}
+		if err := rows.Scan(&inconsistency.RequestID, &inconsistency.User1ID, &inconsistency.User2ID); err != nil {
+			logutils.Error(err.Error())
+			return nil, err
+		}
+		inconsistencies = append(inconsistencies, inconsistency)
+	}
+
+	if err := rows.Err(); err != nil {
+		logutils.Error(err.Error())
+		return nil, err
+	}
+	return inconsistencies, nil
+}
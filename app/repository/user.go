@@ -6,13 +6,24 @@ import (
 	"database/sql"
 	"minimal_sns_app/domain/models"
 	"minimal_sns_app/logutils"
+	"minimal_sns_app/textutils"
+	"sort"
+	"strings"
+	"time"
 )
 
 // UserRepository defines the interface for user data access.
 type UserRepository interface {
 	GetUser(userID int64) (*models.User, error)
+	GetUserByName(name string) (*models.User, error)
+	IsNameTaken(name string) (bool, error)
+	GetUsers(userIDs []int64) ([]models.User, error)
 	CreateUser(name string) (*models.User, error)
-	DeleteUser(userID int64) error
+	UpdateUser(userID int64, update models.UserUpdate) (*models.User, error)
+	SearchUsers(searcherID int64, query string, match string, afterID int64, limit int) ([]models.User, error)
+	DeactivateUser(userID int64) error
+	RestoreUser(userID int64, gracePeriod time.Duration) (*models.User, error)
+	PurgeDeactivatedUsers(gracePeriod time.Duration, batchSize int) (int, error)
 }
 
 type userRepository struct {
@@ -24,12 +35,34 @@ func NewUserRepository(db *sql.DB) UserRepository {
 	return &userRepository{db: db}
 }
 
+// userColumns selects a user with the profile from users, in the order scanned by scanUser.
+const userColumns = `id, name, display_name, bio, COALESCE(DATE_FORMAT(birthday, '%Y-%m-%d'), ''), location, avatar_path,
+		UNIX_TIMESTAMP(created_at), UNIX_TIMESTAMP(updated_at)`
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanUser scans a row selecting userColumns into a user.
+func scanUser(row rowScanner) (*models.User, error) {
+	var user models.User
+	var createdAt, updatedAt int64
+	err := row.Scan(&user.ID, &user.Name, &user.DisplayName, &user.Bio, &user.Birthday, &user.Location, &user.AvatarPath,
+		&createdAt, &updatedAt)
+	if err != nil {
+		return nil, err
+	}
+	user.CreatedAt = time.Unix(createdAt, 0).UTC()
+	user.UpdatedAt = time.Unix(updatedAt, 0).UTC()
+	return &user, nil
+}
+
 // GetUser retrieves a user by ID.
 func (r *userRepository) GetUser(userID int64) (*models.User, error) {
-	var user models.User
-	query := `SELECT id, name FROM users WHERE id = ?`
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = ? AND deactivated_at IS NULL`
 
-	err := r.db.QueryRow(query, userID).Scan(&user.ID, &user.Name)
+	user, err := scanUser(r.db.QueryRow(query, userID))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -37,17 +70,81 @@ func (r *userRepository) GetUser(userID int64) (*models.User, error) {
 		logutils.Error(err.Error())
 		return nil, err
 	}
-	return &user, nil
+	return user, nil
 }
 
-// CreateUser creates a new user.
-func (r *userRepository) CreateUser(name string) (*models.User, error) {
-	query := `INSERT INTO users (name) VALUES (?)`
-	result, err := r.db.Exec(query, name)
+// GetUserByName retrieves a user by name. Names are compared in NFKC form regardless of case.
+func (r *userRepository) GetUserByName(name string) (*models.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE name_key = LOWER(?) AND deactivated_at IS NULL`
+
+	user, err := scanUser(r.db.QueryRow(query, textutils.NormalizeHandle(name)))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		logutils.Error(err.Error())
+		return nil, err
+	}
+	return user, nil
+}
+
+// GetUsers retrieves the users with the given IDs in one query, ordered by ID.
+// IDs no user exists for are skipped, and at most maxIDsPerQuery IDs can be given.
+func (r *userRepository) GetUsers(userIDs []int64) ([]models.User, error) {
+	users := []models.User{}
+	if len(userIDs) == 0 {
+		return users, nil
+	}
+
+	query := `SELECT ` + userColumns + ` FROM users
+			WHERE id IN (` + placeholders(len(userIDs)) + `) AND deactivated_at IS NULL
+			ORDER BY id`
+	rows, err := r.db.Query(query, int64Args(userIDs)...)
 	if err != nil {
 		logutils.Error(err.Error())
 		return nil, err
 	}
+	defer rows.Close()
+
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			logutils.Error(err.Error())
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+
+	if err := rows.Err(); err != nil {
+		logutils.Error(err.Error())
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// IsNameTaken reports whether a user already has name, compared in NFKC form regardless of case.
+// The names of deactivated users stay taken until they are purged, so that they can be restored.
+func (r *userRepository) IsNameTaken(name string) (bool, error) {
+	var taken bool
+	query := `SELECT EXISTS (SELECT 1 FROM users WHERE name_key = LOWER(?))`
+	if err := r.db.QueryRow(query, textutils.NormalizeHandle(name)).Scan(&taken); err != nil {
+		logutils.Error(err.Error())
+		return false, err
+	}
+	return taken, nil
+}
+
+// CreateUser creates a new user, storing the name in NFKC form. The name is expected to follow textutils.CheckHandle.
+// It fails with ErrDuplicate when the name is already taken, whatever its case.
+func (r *userRepository) CreateUser(name string) (*models.User, error) {
+	name = textutils.NormalizeHandle(name)
+	query := `INSERT INTO users (name, search_name) VALUES (?, ?)`
+	result, err := r.db.Exec(query, name, textutils.SearchKey(name))
+	if err != nil {
+		logutils.Error(err.Error())
+		return nil, translateError(err)
+	}
 
 	userID, err := result.LastInsertId()
 	if err != nil {
@@ -58,13 +155,234 @@ func (r *userRepository) CreateUser(name string) (*models.User, error) {
 	return r.GetUser(userID)
 }
 
-// DeleteUser deletes a user by ID.
-func (r *userRepository) DeleteUser(userID int64) error {
-	query := `DELETE FROM users WHERE id = ?`
-	_, err := r.db.Exec(query, userID)
+// UpdateUser changes the profile fields of a user set in update and returns the updated user.
+// The name is not part of the profile and cannot be changed. It fails with ErrNotFound when the user does not exist.
+func (r *userRepository) UpdateUser(userID int64, update models.UserUpdate) (*models.User, error) {
+	var sets []string
+	var args []interface{}
+	fields := []struct {
+		column string
+		value  *string
+	}{
+		{"display_name", update.DisplayName},
+		{"bio", update.Bio},
+		{"location", update.Location},
+		{"avatar_path", update.AvatarPath},
+	}
+	for _, field := range fields {
+		if field.value != nil {
+			sets = append(sets, field.column+" = ?")
+			args = append(args, *field.value)
+		}
+	}
+	if update.Birthday != nil {
+		sets = append(sets, "birthday = NULLIF(?, '')")
+		args = append(args, *update.Birthday)
+	}
+
+	if len(sets) > 0 {
+		query := `UPDATE users SET ` + strings.Join(sets, ", ") + ` WHERE id = ? AND deactivated_at IS NULL`
+		if _, err := r.db.Exec(query, append(args, userID)...); err != nil {
+			logutils.Error(err.Error())
+			return nil, err
+		}
+	}
+
+	user, err := r.GetUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		logutils.Error("User not found")
+		return nil, ErrNotFound
+	}
+	return user, nil
+}
+
+// SearchUsers retrieves up to limit users whose name matches query, ordered by ID from afterID on.
+// The name and query are compared by textutils.SearchKey, so width, case and kana differences are ignored.
+// match is models.UserSearchPrefix, which uses the index on search_name, or models.UserSearchSubstring, which scans it.
+// Users who blocked the searcher are left out.
+// Only search_name is searched, so every write path creating a user or changing a name has to set it
+// to textutils.SearchKey(name) as CreateUser does; a user left with the empty default is never found.
+func (r *userRepository) SearchUsers(searcherID int64, query string, match string, afterID int64, limit int) ([]models.User, error) {
+	pattern := textutils.EscapeLike(textutils.SearchKey(query)) + "%"
+	if match == models.UserSearchSubstring {
+		pattern = "%" + pattern
+	}
+
+	sqlQuery := `SELECT ` + userColumns + ` FROM users AS u
+			WHERE u.search_name LIKE ? AND u.id > ? AND u.deactivated_at IS NULL
+			AND NOT EXISTS (SELECT 1 FROM block_list AS bl WHERE bl.user1_id = u.id AND bl.user2_id = ?)
+			ORDER BY u.id
+			LIMIT ?`
+	rows, err := r.db.Query(sqlQuery, pattern, afterID, searcherID, limit)
+	if err != nil {
+		logutils.Error(err.Error())
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			logutils.Error(err.Error())
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+
+	if err := rows.Err(); err != nil {
+		logutils.Error(err.Error())
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// A deactivated user keeps every row referencing it, but is left out of every query the other users see:
+// it cannot be looked up, searched, listed as a friend or reached through friends. Restoring it brings all of that back.
+// Once the grace period is over the user is deleted for good, and ON DELETE CASCADE removes the rest.
+
+// DeactivateUser deactivates a user by ID.
+// It fails with ErrNotFound when the user does not exist or is already deactivated.
+func (r *userRepository) DeactivateUser(userID int64) error {
+	query := `UPDATE users SET deactivated_at = NOW() WHERE id = ? AND deactivated_at IS NULL`
+	result, err := r.db.Exec(query, userID)
+	if err != nil {
+		logutils.Error(err.Error())
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		logutils.Error(err.Error())
+		return err
+	}
+	if affected == 0 {
+		logutils.Error("User not found")
+		return ErrNotFound
+	}
+	return nil
+}
+
+// RestoreUser restores a user deactivated less than gracePeriod ago and returns it.
+// It fails with ErrNotFound when there is no such user.
+func (r *userRepository) RestoreUser(userID int64, gracePeriod time.Duration) (*models.User, error) {
+	query := `UPDATE users SET deactivated_at = NULL
+			WHERE id = ? AND deactivated_at > NOW() - INTERVAL ? SECOND`
+	result, err := r.db.Exec(query, userID, int64(gracePeriod.Seconds()))
+	if err != nil {
+		logutils.Error(err.Error())
+		return nil, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		logutils.Error(err.Error())
+		return nil, err
+	}
+	if affected == 0 {
+		logutils.Error("Deactivated user not found")
+		return nil, ErrNotFound
+	}
+	return r.GetUser(userID)
+}
+
+// PurgeDeactivatedUsers deletes the users deactivated more than gracePeriod ago and returns how many were deleted.
+// The users are deleted batchSize at a time, so that the cascade of a large backlog does not hold locks for long.
+// A batch size that is not positive returns ErrInvalidBatchSize.
+func (r *userRepository) PurgeDeactivatedUsers(gracePeriod time.Duration, batchSize int) (int, error) {
+	if batchSize <= 0 {
+		return 0, ErrInvalidBatchSize
+	}
+	query := `DELETE FROM users
+			WHERE deactivated_at < NOW() - INTERVAL ? SECOND
+			ORDER BY deactivated_at
+			LIMIT ?`
+	purged := 0
+	for {
+		result, err := r.db.Exec(query, int64(gracePeriod.Seconds()), batchSize)
+		if err != nil {
+			logutils.Error(err.Error())
+			return purged, err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			logutils.Error(err.Error())
+			return purged, err
+		}
+		purged += int(affected)
+		if int(affected) < batchSize {
+			return purged, nil
+		}
+	}
+}
+
+// isActiveUser reports whether a user exists and is not deactivated, locking the user row against a concurrent deactivation.
+func isActiveUser(tx *sql.Tx, userID int64) (bool, error) {
+	var id int64
+	query := `SELECT id FROM users WHERE id = ? AND deactivated_at IS NULL FOR SHARE`
+	if err := tx.QueryRow(query, userID).Scan(&id); err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		logutils.Error(err.Error())
+		return false, err
+	}
+	return true, nil
+}
+
+// areActiveUsers reports whether all the given users exist and are not deactivated, locking their rows like isActiveUser.
+// The rows are locked in ID order, the order lockUserPair uses, so that transactions locking the same users cannot deadlock.
+func areActiveUsers(tx *sql.Tx, userIDs ...int64) (bool, error) {
+	userIDs = append([]int64(nil), userIDs...)
+	sort.Slice(userIDs, func(i, j int) bool { return userIDs[i] < userIDs[j] })
+	for _, userID := range userIDs {
+		active, err := isActiveUser(tx, userID)
+		if err != nil || !active {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// checkActiveUser fails with ErrNotFound unless the user exists and is not deactivated.
+// Read queries call it for the other user they are about, so that a deactivated user is not revealed through them.
+func checkActiveUser(db *sql.DB, userID int64) error {
+	var active bool
+	query := `SELECT EXISTS (SELECT 1 FROM users WHERE id = ? AND deactivated_at IS NULL)`
+	if err := db.QueryRow(query, userID).Scan(&active); err != nil {
+		logutils.Error(err.Error())
+		return err
+	}
+	if !active {
+		logutils.Error("User not found")
+		return ErrNotFound
+	}
+	return nil
+}
+
+// lockUserPair locks the rows of two users in ID order, so that transactions locking the same pair wait for
+// each other instead of deadlocking. Users that do not exist are left for the caller to report.
+func lockUserPair(tx *sql.Tx, userID int64, otherID int64) error {
+	query := `SELECT id FROM users WHERE id IN (?, ?) ORDER BY id FOR UPDATE`
+	rows, err := tx.Query(query, userID, otherID)
 	if err != nil {
 		logutils.Error(err.Error())
 		return err
 	}
+	defer rows.Close()
+	for rows.Next() {
+	}
+	if err := rows.Err(); err != nil {
+		logutils.Error(err.Error())
+		return err
+	}
 	return nil
 }
+
+// activeUserCondition returns a WHERE condition that drops rows whose column is a deactivated user.
+// Queries selecting from users directly check deactivated_at instead.
+func activeUserCondition(column string) string {
+	return `NOT EXISTS (SELECT 1 FROM users AS du WHERE du.id = ` + column + ` AND du.deactivated_at IS NOT NULL)`
+}
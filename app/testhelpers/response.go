@@ -0,0 +1,38 @@
+package testhelpers
+
+import (
+	"encoding/json"
+	"minimal_sns_app/handlers"
+	"testing"
+)
+
+// Envelope is the response envelope with its data left undecoded.
+type Envelope struct {
+	Data  json.RawMessage       `json:"data"`
+	Meta  *handlers.Meta        `json:"meta"`
+	Error *handlers.ErrorDetail `json:"error"`
+}
+
+// DecodeResponse is a test helper function to decode a response envelope and its data into data.
+// data may be nil when only the envelope is needed.
+func DecodeResponse(t *testing.T, body []byte, data interface{}) Envelope {
+	t.Helper()
+	var envelope Envelope
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		t.Fatalf("failed to unmarshal response envelope: %v", err)
+	}
+	if data != nil && envelope.Data != nil {
+		if err := json.Unmarshal(envelope.Data, data); err != nil {
+			t.Fatalf("failed to unmarshal response data: %v", err)
+		}
+	}
+	return envelope
+}
+
+// DecodeMessage is a test helper function to decode the message of a response envelope.
+func DecodeMessage(t *testing.T, body []byte) string {
+	t.Helper()
+	var message handlers.Message
+	DecodeResponse(t, body, &message)
+	return message.Message
+}
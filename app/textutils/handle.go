@@ -0,0 +1,58 @@
+package textutils
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Number of characters a handle can hold once normalized.
+const (
+	MinHandleLength = 3
+	MaxHandleLength = 30
+)
+
+var (
+	// ErrInvalidHandle is returned for handles that are too short, too long or use characters outside of the policy.
+	ErrInvalidHandle = errors.New("invalid handle")
+	// ErrReservedHandle is returned for handles kept for the service itself.
+	ErrReservedHandle = errors.New("reserved handle")
+)
+
+// reservedHandles are the handles nobody can take, in lowercase: they name routes or could pass for the service.
+var reservedHandles = map[string]bool{
+	"admin": true, "administrator": true, "root": true, "system": true, "support": true, "help": true,
+	"official": true, "staff": true, "moderator": true, "api": true, "www": true, "mail": true,
+	"user": true, "users": true, "me": true, "settings": true, "login": true, "logout": true, "signup": true,
+	"null": true, "undefined": true, "anonymous": true, "unknown": true, "deleted": true,
+}
+
+// NormalizeHandle returns the NFKC form of a handle, the form handles are stored, looked up and checked in,
+// so that full-width and half-width spellings are the same handle.
+func NormalizeHandle(name string) string {
+	return norm.NFKC.String(name)
+}
+
+// CheckHandle checks a normalized handle against the handle policy: between MinHandleLength and MaxHandleLength
+// letters, digits and underscores, and not a reserved handle whatever its case.
+func CheckHandle(name string) error {
+	if !utf8.ValidString(name) {
+		return ErrInvalidHandle
+	}
+	length := utf8.RuneCountInString(name)
+	if length < MinHandleLength || length > MaxHandleLength {
+		return ErrInvalidHandle
+	}
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+			return ErrInvalidHandle
+		}
+	}
+	if reservedHandles[strings.ToLower(name)] {
+		return ErrReservedHandle
+	}
+	return nil
+}
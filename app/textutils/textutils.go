@@ -0,0 +1,45 @@
+package textutils
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// SearchKey folds s into the form user names are searched by, so that the spellings a user may type
+// for the same name match each other:
+//   - full-width and half-width forms are unified by NFKC (ＡＢＣ and ABC, ｱｲｳ and アイウ)
+//   - letters are lowercased
+//   - katakana is turned into hiragana (アイウ and あいう)
+func SearchKey(s string) string {
+	s = strings.ToLower(norm.NFKC.String(s))
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'ァ' && r <= 'ヶ', r == 'ヽ' || r == 'ヾ':
+			return r - ('ァ' - 'ぁ')
+		}
+		return r
+	}, s)
+}
+
+// EscapeLike escapes the characters LIKE treats as wildcards, along with its escape character, so that s matches literally.
+func EscapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// FreeText trims a free text field and reports whether it is valid: valid UTF-8 of at most maxLength characters,
+// without control or format characters other than line feeds when multiline is set. An empty value is valid.
+func FreeText(value string, maxLength int, multiline bool) (string, bool) {
+	value = strings.TrimSpace(value)
+	if !utf8.ValidString(value) || utf8.RuneCountInString(value) > maxLength {
+		return "", false
+	}
+	for _, r := range value {
+		if (r != '\n' || !multiline) && (unicode.IsControl(r) || unicode.Is(unicode.Cf, r)) {
+			return "", false
+		}
+	}
+	return value, true
+}
@@ -1,13 +1,39 @@
+-- name is the unique handle of a user in NFKC form, the other columns make up the profile they can edit.
+-- name_key makes handles unique regardless of case: Alice and alice cannot both exist.
+-- name itself compares in binary, so that name_key alone defines which handles clash: the default collation
+-- would also equate accented and unaccented letters (cafe and café) and hiragana and katakana (ありす and アリス).
+-- search_name is name folded by textutils.SearchKey, kept by the application so that searches can use its index:
+-- every insert of a user, seed data included, has to set it or the user can never be found.
+-- deactivated_at is set while a user is deactivated: the user is hidden everywhere but can be restored
+-- until the grace period is over, when the row is deleted along with everything referencing it.
 CREATE TABLE `users` (
   `id` bigint(20) NOT NULL AUTO_INCREMENT,
-  `name` varchar(64) NOT NULL UNIQUE,
-  PRIMARY KEY (`id`)
+  `name` varchar(64) COLLATE utf8mb4_bin NOT NULL UNIQUE,
+  `name_key` varchar(64) COLLATE utf8mb4_bin GENERATED ALWAYS AS (LOWER(`name`)) STORED NOT NULL UNIQUE,
+  `search_name` varchar(255) NOT NULL DEFAULT '',
+  `display_name` varchar(64) NOT NULL DEFAULT '',
+  `bio` varchar(500) NOT NULL DEFAULT '',
+  `birthday` date DEFAULT NULL,
+  `location` varchar(100) NOT NULL DEFAULT '',
+  `avatar_path` varchar(255) NOT NULL DEFAULT '',
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  `deactivated_at` datetime DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_search_name` (`search_name`),
+  INDEX `idx_deactivated_at` (`deactivated_at`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
 
+-- A friendship is stored as two rows, one per direction: the row (user1_id, user2_id) holds
+-- what user1 sees of the friendship with user2, such as whether user2 is a close friend.
 CREATE TABLE `friend_link` (
   `user1_id` bigint(20) NOT NULL,
   `user2_id` bigint(20) NOT NULL,
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `close_friend` tinyint(1) NOT NULL DEFAULT 0,
+  `nickname` varchar(64) DEFAULT NULL,
   PRIMARY KEY (`user1_id`, `user2_id`),
+  INDEX `idx_user1_created_at` (`user1_id`, `created_at`),
   CONSTRAINT `fk_friend_link_user1` FOREIGN KEY (`user1_id`) REFERENCES `users` (`id`) ON DELETE CASCADE,
   CONSTRAINT `fk_friend_link_user2` FOREIGN KEY (`user2_id`) REFERENCES `users` (`id`) ON DELETE CASCADE,
   CHECK (`user1_id` != `user2_id`)
@@ -22,15 +48,60 @@ CREATE TABLE `block_list` (
   CHECK (`user1_id` != `user2_id`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
 
+CREATE TABLE `follow` (
+  `follower_id` bigint(20) NOT NULL,
+  `followee_id` bigint(20) NOT NULL,
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`follower_id`, `followee_id`),
+  INDEX `idx_followee_follower` (`followee_id`, `follower_id`),
+  CONSTRAINT `fk_follow_follower` FOREIGN KEY (`follower_id`) REFERENCES `users` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `fk_follow_followee` FOREIGN KEY (`followee_id`) REFERENCES `users` (`id`) ON DELETE CASCADE,
+  CHECK (`follower_id` != `followee_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE `friend_lists` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `owner_id` bigint(20) NOT NULL,
+  `name` varchar(64) NOT NULL,
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_owner_name` (`owner_id`, `name`),
+  CONSTRAINT `fk_friend_lists_owner` FOREIGN KEY (`owner_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE `friend_list_members` (
+  `list_id` bigint(20) NOT NULL,
+  `member_id` bigint(20) NOT NULL,
+  PRIMARY KEY (`list_id`, `member_id`),
+  INDEX `idx_member_id` (`member_id`),
+  CONSTRAINT `fk_friend_list_members_list` FOREIGN KEY (`list_id`) REFERENCES `friend_lists` (`id`) ON DELETE CASCADE,
+  CONSTRAINT `fk_friend_list_members_member` FOREIGN KEY (`member_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
 CREATE TABLE `friend_requests` (
   `id` bigint(20) NOT NULL AUTO_INCREMENT,
   `requester_id` bigint(20) NOT NULL,
   `requested_id` bigint(20) NOT NULL,
-  `status` enum('pending','accepted','declined') NOT NULL DEFAULT 'pending',
+  `status` enum('pending','accepted','declined','canceled','expired','ended') NOT NULL DEFAULT 'pending',
+  `message` varchar(200) NOT NULL DEFAULT '',
   `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
   `updated_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  -- 1 while pending and NULL afterwards, so that only one pending request can exist per direction
+  `pending` tinyint(1) GENERATED ALWAYS AS (IF(`status` = 'pending', 1, NULL)) STORED,
   PRIMARY KEY (`id`),
-  UNIQUE INDEX `idx_requester_requested_status` (`requester_id`, `requested_id`, `status`),
+  UNIQUE INDEX `idx_requester_requested_pending` (`requester_id`, `requested_id`, `pending`),
+  INDEX `idx_requested_status` (`requested_id`, `status`),
+  INDEX `idx_status_created_at` (`status`, `created_at`),
   CONSTRAINT `fk_friend_requests_requester` FOREIGN KEY (`requester_id`) REFERENCES `users` (`id`) ON DELETE CASCADE,
   CONSTRAINT `fk_friend_requests_requested` FOREIGN KEY (`requested_id`) REFERENCES `users` (`id`) ON DELETE CASCADE
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE `friend_request_events` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `request_id` bigint(20) NOT NULL,
+  `status` enum('pending','accepted','declined','canceled','expired','ended') NOT NULL,
+  `created_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  INDEX `idx_request_id` (`request_id`),
+  CONSTRAINT `fk_friend_request_events_request` FOREIGN KEY (`request_id`) REFERENCES `friend_requests` (`id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
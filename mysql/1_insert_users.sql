@@ -1,5 +1,6 @@
-INSERT INTO users (name) VALUES
-('User1'),
-('User2'),
-('User3'),
-('User4');
\ No newline at end of file
+-- search_name holds name folded by textutils.SearchKey, which is the lowercase name for these ASCII names
+INSERT INTO users (name, search_name) VALUES
+('User1', 'user1'),
+('User2', 'user2'),
+('User3', 'user3'),
+('User4', 'user4');